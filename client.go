@@ -25,13 +25,16 @@ package naml
 
 import (
 	"fmt"
-	"path"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"k8s.io/client-go/util/homedir"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 const (
@@ -40,46 +43,79 @@ const (
 	KubeconfigDefaultFile           = "config"
 )
 
+// InClusterTokenFile is the service account token that is mounted in
+// every pod with a service account
+const InClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// inCluster is the --in-cluster value
+var inCluster bool
+
 // cachedClient is package level state that will cache the Clienset
 var cachedClient *kubernetes.Clientset
 
-// kubeConfigPathValue is a very flexible string, so we have to handle it
-// carefully. It can be either the environmental variable, or the CLI flag
-//
-// KUBECONFIG environmental variable
+// kubeConfigPathValue is the --kubeconfig flag. When set it is the only
+// kubeconfig that is loaded, like kubectl. When empty the KUBECONFIG
+// environmental variable is a list of kubeconfigs that are merged, and
+// ~/.kube/config is used when KUBECONFIG is not set.
 //
-// This can be a list of configs such as:
-//     config:config-sample:config-boops
-//
-// --kubeconfig
-// This can be a single path such as
-//     ~/.kube/config
-//     /home/me/.kube/config
-//     config (which should assume ${HOME}.kube/config)
+// KUBECONFIG is separated by ":" (";" on Windows), such as:
+//     ~/.kube/config:/etc/kubernetes/admin.conf
 var kubeConfigPathValue string
 
+// kubeConfigOverrides are the --context, --cluster and --user values
+// that override the current context of the kubeconfig
+var kubeConfigOverrides = &clientcmd.ConfigOverrides{}
+
+// SetKubeContext will select the context, cluster and user of the kubeconfig
+// for every client. An empty value will use the current context of the
+// kubeconfig.
+func SetKubeContext(context, cluster, user string) {
+	kubeConfigOverrides = &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+		Context: clientcmdapi.Context{
+			Cluster:  cluster,
+			AuthInfo: user,
+		},
+	}
+	cachedClient = nil
+}
+
+// SetInCluster will always use the service account of the pod for
+// every client, and never a kubeconfig.
+func SetInCluster(enabled bool) {
+	inCluster = enabled
+	cachedClient = nil
+}
+
+// InCluster will return true when running in a pod with a
+// service account token.
+func InCluster() bool {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" || os.Getenv("KUBERNETES_SERVICE_PORT") == "" {
+		return false
+	}
+	_, err := os.Stat(InClusterTokenFile)
+	return err == nil
+}
+
 // Client is used to authenticate with Kubernetes and build the Kube client
 // for the rest of the program.
+//
+// The kubeconfig is loaded with the same loading rules as kubectl. When no
+// kubeconfig is found and naml is running in a pod, or with --in-cluster,
+// the service account of the pod is used.
 func Client() (*kubernetes.Clientset, error) {
 	if cachedClient != nil {
 		return cachedClient, nil
 	}
-
-	// Windows support requires us to split on ";" instead of ":"
-	// I personally have no use case to care for this. Pull requests accepted.
-	configs := strings.Split(kubeConfigPathValue, ":")
-	if len(configs) > 1 {
-		for _, config := range configs {
-			client, err := ClientFromPath(path.Join(homedir.HomeDir(), KubeconfigDefaultDirectory, config))
-			if err == nil {
-				// Just pick the first one
-				return client, nil
-			}
+	if inCluster {
+		client, err := ClientInCluster()
+		if err != nil {
+			return nil, err
 		}
-		// If we get here, we have no valid config
-		// We can just silently try and fail...
+		cachedClient = client
+		return cachedClient, nil
 	}
-	client, err := ClientFromPath(kubeConfigPathValue)
+	client, err := ClientWithOverrides(kubeConfigPathValue, kubeConfigOverrides)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load kube config: %v", err)
 	}
@@ -92,7 +128,12 @@ func Client() (*kubernetes.Clientset, error) {
 //
 // Useful for testing.
 func ClientFromPath(kubeConfigPath string) (*kubernetes.Clientset, error) {
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	return ClientWithOverrides(kubeConfigPath, &clientcmd.ConfigOverrides{})
+}
+
+// ClientFromFlags will plumb well-known command line flags through to the kubeconfig
+func ClientFromFlags(apiUrl, kubeConfigPath string) (*kubernetes.Clientset, error) {
+	config, err := clientcmd.BuildConfigFromFlags(apiUrl, kubeConfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("unable to find local kube config [%s]: %v", kubeConfigPath, err)
 	}
@@ -103,11 +144,31 @@ func ClientFromPath(kubeConfigPath string) (*kubernetes.Clientset, error) {
 	return client, nil
 }
 
-// ClientFromFlags will plumb well-known command line flags through to the kubeconfig
-func ClientFromFlags(apiUrl, kubeConfigPath string) (*kubernetes.Clientset, error) {
-	config, err := clientcmd.BuildConfigFromFlags(apiUrl, kubeConfigPath)
+// ClientInCluster will build a client with the service account of the pod.
+func ClientInCluster() (*kubernetes.Clientset, error) {
+	config, err := rest.InClusterConfig()
 	if err != nil {
-		return nil, fmt.Errorf("unable to find local kube config [%s]: %v", kubeConfigPath, err)
+		return nil, fmt.Errorf("unable to load in cluster config: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build kube config: %v", err)
+	}
+	return client, nil
+}
+
+// ClientForContext will build a client for a named context of a kubeconfig
+// instead of the current context.
+func ClientForContext(kubeConfigPath, context string) (*kubernetes.Clientset, error) {
+	return ClientWithOverrides(kubeConfigPath, &clientcmd.ConfigOverrides{CurrentContext: context})
+}
+
+// ClientWithOverrides will build a client for a kubeconfig with overrides,
+// such as the context, cluster or user to use.
+func ClientWithOverrides(kubeConfigPath string, overrides *clientcmd.ConfigOverrides) (*kubernetes.Clientset, error) {
+	config, err := restConfig(kubeConfigPath, overrides)
+	if err != nil {
+		return nil, err
 	}
 	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -115,3 +176,34 @@ func ClientFromFlags(apiUrl, kubeConfigPath string) (*kubernetes.Clientset, erro
 	}
 	return client, nil
 }
+
+// LoadingRules will return the kubectl loading rules for a kubeconfig path.
+//
+// An empty path will merge every kubeconfig in KUBECONFIG, or use
+// ~/.kube/config when KUBECONFIG is not set.
+func LoadingRules(kubeConfigPath string) *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = ExpandHome(kubeConfigPath)
+	return rules
+}
+
+// ExpandHome will replace a leading "~" in a path with the home directory.
+func ExpandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		return filepath.Join(homedir.HomeDir(), p[1:])
+	}
+	return p
+}
+
+// restConfig will load a kubeconfig with the clientcmd overrides.
+//
+// When no kubeconfig is found and InCluster() is true, clientcmd
+// will use the service account of the pod.
+func restConfig(kubeConfigPath string, overrides *clientcmd.ConfigOverrides) (*rest.Config, error) {
+	rules := LoadingRules(kubeConfigPath)
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find local kube config [%s]: %v", strings.Join(rules.GetLoadingPrecedence(), string(filepath.ListSeparator)), err)
+	}
+	return config, nil
+}
@@ -0,0 +1,231 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/homedir"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+users:
+- name: dev
+  user:
+    token: dev-token
+- name: prod
+  user:
+    token: prod-token
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+    namespace: web
+- name: prod
+  context:
+    cluster: prod
+    user: prod
+`
+
+const testKubeconfigStaging = `apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+- name: staging
+  cluster:
+    server: https://staging.example.com
+- name: dev
+  cluster:
+    server: https://other.example.com
+users:
+- name: staging
+  user:
+    token: staging-token
+contexts:
+- name: staging
+  context:
+    cluster: staging
+    user: staging
+`
+
+func writeKubeconfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	err := ioutil.WriteFile(path, []byte(data), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setenv will set an environmental variable and return a func
+// that restores the original value, to be deferred.
+func setenv(key, value string) func() {
+	original, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, original)
+			return
+		}
+		os.Unsetenv(key)
+	}
+}
+
+func TestClientOverrides(t *testing.T) {
+	path := writeKubeconfig(t, testKubeconfig)
+
+	for _, test := range []struct {
+		name      string
+		overrides *clientcmd.ConfigOverrides
+		host      string
+		token     string
+	}{
+		{"current context", &clientcmd.ConfigOverrides{}, "https://dev.example.com", "dev-token"},
+		{"context", &clientcmd.ConfigOverrides{CurrentContext: "prod"}, "https://prod.example.com", "prod-token"},
+	} {
+		config, err := restConfig(path, test.overrides)
+		if err != nil {
+			t.Fatalf("%s: unable to load config: %v", test.name, err)
+		}
+		if config.Host != test.host || config.BearerToken != test.token {
+			t.Errorf("%s: expected %s %s, got %s %s", test.name, test.host, test.token, config.Host, config.BearerToken)
+		}
+	}
+
+	// The cluster and user of a context can be overridden
+	SetKubeContext("dev", "prod", "prod")
+	defer SetKubeContext("", "", "")
+	config, err := restConfig(path, kubeConfigOverrides)
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+	if config.Host != "https://prod.example.com" || config.BearerToken != "prod-token" {
+		t.Errorf("expected prod cluster and user, got %s %s", config.Host, config.BearerToken)
+	}
+
+	_, err = ClientForContext(path, "prod")
+	if err != nil {
+		t.Errorf("unable to build client for context: %v", err)
+	}
+	_, err = ClientForContext(path, "missing")
+	if err == nil {
+		t.Errorf("expected error for a missing context")
+	}
+}
+
+func TestClientLoadingRules(t *testing.T) {
+	dev := writeKubeconfig(t, testKubeconfig)
+	staging := writeKubeconfig(t, testKubeconfigStaging)
+
+	// KUBECONFIG is merged, and the first file wins
+	defer setenv(KubeconfigEnvironmentalVariable, dev+string(filepath.ListSeparator)+staging)()
+	config, err := restConfig("", &clientcmd.ConfigOverrides{})
+	if err != nil {
+		t.Fatalf("unable to load merged config: %v", err)
+	}
+	if config.Host != "https://dev.example.com" {
+		t.Errorf("expected the current context and clusters of the first file, got %s", config.Host)
+	}
+	config, err = restConfig("", &clientcmd.ConfigOverrides{CurrentContext: "staging"})
+	if err != nil {
+		t.Fatalf("unable to load a context of the second file: %v", err)
+	}
+	if config.Host != "https://staging.example.com" || config.BearerToken != "staging-token" {
+		t.Errorf("expected staging, got %s %s", config.Host, config.BearerToken)
+	}
+
+	// Missing files in KUBECONFIG are skipped
+	defer setenv(KubeconfigEnvironmentalVariable, filepath.Join(t.TempDir(), "missing")+string(filepath.ListSeparator)+staging)()
+	config, err = restConfig("", &clientcmd.ConfigOverrides{})
+	if err != nil {
+		t.Fatalf("unable to load config with a missing file: %v", err)
+	}
+	if config.Host != "https://staging.example.com" {
+		t.Errorf("expected staging, got %s", config.Host)
+	}
+
+	// --kubeconfig is the only file that is loaded
+	config, err = restConfig(dev, &clientcmd.ConfigOverrides{})
+	if err != nil {
+		t.Fatalf("unable to load explicit config: %v", err)
+	}
+	if config.Host != "https://dev.example.com" {
+		t.Errorf("expected dev, got %s", config.Host)
+	}
+	_, err = restConfig(dev, &clientcmd.ConfigOverrides{CurrentContext: "staging"})
+	if err == nil {
+		t.Errorf("expected KUBECONFIG to be ignored with an explicit kubeconfig")
+	}
+	_, err = restConfig(filepath.Join(t.TempDir(), "missing"), &clientcmd.ConfigOverrides{})
+	if err == nil {
+		t.Errorf("expected error for a missing explicit kubeconfig")
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := homedir.HomeDir()
+	for p, expected := range map[string]string{
+		"~/.kube/config":    filepath.Join(home, ".kube/config"),
+		"~":                 home,
+		"/etc/kube/config":  "/etc/kube/config",
+		"/tmp/~user/config": "/tmp/~user/config",
+		"relative/~/config": "relative/~/config",
+		"":                  "",
+	} {
+		if actual := ExpandHome(p); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, p, actual)
+		}
+	}
+}
+
+func TestClientInCluster(t *testing.T) {
+	defer setenv("KUBERNETES_SERVICE_HOST", "")()
+	defer setenv("KUBERNETES_SERVICE_PORT", "")()
+	if InCluster() {
+		t.Fatalf("expected not to be in cluster without the service environment")
+	}
+
+	// --in-cluster never falls back to a kubeconfig
+	defer setenv(KubeconfigEnvironmentalVariable, writeKubeconfig(t, testKubeconfig))()
+	SetInCluster(true)
+	defer SetInCluster(false)
+	_, err := Client()
+	if err == nil {
+		t.Errorf("expected error for --in-cluster outside of a pod")
+	}
+}
@@ -25,13 +25,15 @@ package naml
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
-	"k8s.io/client-go/util/homedir"
-
 	"github.com/kris-nova/logger"
 	"github.com/urfave/cli/v2"
 )
@@ -66,6 +68,14 @@ func RunCommandLineWithOptions() error {
 	// which is used in our Client() code
 	var kubeconfig string
 
+	// kubeContext, kubeCluster and kubeUser are the --context, --cluster
+	// and --user values that override the current context of the kubeconfig
+	var kubeContext, kubeCluster, kubeUser string
+
+	// inCluster is the --in-cluster value which will always use
+	// the service account of the pod instead of a kubeconfig
+	var inCluster bool
+
 	// codifyAppNameRaw is the app name passed in the raw form
 	var codifyAppNameRaw string
 
@@ -73,17 +83,85 @@ func RunCommandLineWithOptions() error {
 	// this is ALSO used in the "build" command
 	var output string
 
+	// outputDir will write the output subcommand to a directory
+	// with one file per object instead of stdout.
+	var outputDir string
+
+	// outputList will wrap the output subcommand objects
+	// in a single v1.List.
+	var outputList bool
+
+	// failOnLatest will fail the images subcommand if any
+	// image uses the latest tag.
+	var failOnLatest bool
+
+	// rbacServiceAccount and rbacNamespace are the ServiceAccount
+	// that the rbac subcommand grants permissions to
+	var rbacServiceAccount, rbacNamespace string
+
+	// profileName is the --profile value such as "prod"
+	var profileName string
+
+	// patchFiles are the --patch strategic merge patch files
+	var patchFiles cli.StringSlice
+
+	// jsonPatches are the --json-patch values such as "Deployment/web=patch.json"
+	var jsonPatches cli.StringSlice
+
+	// valuesFiles are the --values files for Configurable apps
+	var valuesFiles cli.StringSlice
+
+	// valueSets are the --set path=value values for Configurable apps
+	var valueSets cli.StringSlice
+
+	// namespace is the -n/--namespace value that every
+	// namespaced object is moved to
+	var namespace string
+
+	// transforms are the --transform values such as "namespace=production"
+	var transforms cli.StringSlice
+
+	// imageRewriteRules are the --image-rewrite rules
+	// such as "docker.io/* => registry.corp/dockerhub/*"
+	var imageRewriteRules cli.StringSlice
+
+	// imageRewriteFile is the --image-rewrite-file value
+	var imageRewriteFile string
+
 	// library will toggle library mode for codify. When
 	// set to true library will generate library code instead
 	// of a program with a main function.
 	var library bool
 
+	// secretLookup will toggle secret lookup mode for codify. When
+	// set to true Secret values will be looked up at install time
+	// instead of being written to the generated code.
+	var secretLookup bool
+
+	// verify will toggle verify mode for codify. When set
+	// to true the generated code will be compiled and executed
+	// and the output compared with the input YAML.
+	var verify bool
+
+	// hoist will toggle hoisting for codify. When set to
+	// true values that are repeated across objects will be
+	// defined once, as package level variables for structs
+	// and as functions for maps and slices.
+	var hoist bool
+
+	// templateSchema will print the schema for the codify
+	// templates instead of generating code.
+	var templateSchema bool
+
+	// functions will toggle function mode for codify. When
+	// set to true every object will be generated in its own
+	// constructor function instead of inline in Install().
+	var functions bool
+
 	// packageName can be used to override the packageName for codify.
 	var packageName string
 
 	codifyValues := &CodifyValues{
-		AuthorEmail:   "<kris@nivenly.com>",
-		AuthorName:    "Kris Nóva",
 		CopyrightYear: fmt.Sprintf("%d", time.Now().Year()),
 		AppNameLower:  "app",
 		AppNameTitle:  "App",
@@ -121,9 +199,28 @@ func RunCommandLineWithOptions() error {
 		naml install   <app>
 		naml uninstall <app>
 `,
+		Before: func(context *cli.Context) error {
+			SetKubeContext(kubeContext, kubeCluster, kubeUser)
+			SetInCluster(inCluster)
+			SetProfile(profileName)
+			err := valuesInit(valuesFiles.Value(), valueSets.Value())
+			if err != nil {
+				return err
+			}
+			err = patchInit(patchFiles.Value(), jsonPatches.Value())
+			if err != nil {
+				return err
+			}
+			err = transformerInit(transforms.Value())
+			if err != nil {
+				return err
+			}
+			namespaceInit(namespace)
+			return imageRewriteInit(imageRewriteRules.Value(), imageRewriteFile)
+		},
 		Action: func(context *cli.Context) error {
 			if output != "" {
-				return outputFunc(output, context)
+				return outputFunc(output, false, context)
 			}
 			Banner()
 			cli.ShowSubcommandHelp(context)
@@ -139,10 +236,34 @@ func RunCommandLineWithOptions() error {
 			},
 			&cli.StringFlag{
 				Name:        "kubeconfig",
-				Value:       "~/.kube/config",
-				Usage:       "Kubeconfig path (default: ~/.kube/config)",
+				Value:       "",
+				Usage:       "Kubeconfig path (default: $KUBECONFIG or ~/.kube/config)",
 				Destination: &kubeconfig,
 			},
+			&cli.BoolFlag{
+				Name:        "in-cluster",
+				Value:       false,
+				Usage:       "Use the service account of the pod instead of a kubeconfig. Detected when no kubeconfig is found in a pod",
+				Destination: &inCluster,
+			},
+			&cli.StringFlag{
+				Name:        "context",
+				Value:       "",
+				Usage:       "The kubeconfig context to use instead of the current context",
+				Destination: &kubeContext,
+			},
+			&cli.StringFlag{
+				Name:        "cluster",
+				Value:       "",
+				Usage:       "The kubeconfig cluster to use instead of the cluster of the context",
+				Destination: &kubeCluster,
+			},
+			&cli.StringFlag{
+				Name:        "user",
+				Value:       "",
+				Usage:       "The kubeconfig user to use instead of the user of the context",
+				Destination: &kubeUser,
+			},
 			&cli.StringFlag{
 				Name:        "output",
 				Aliases:     []string{"o"},
@@ -150,6 +271,61 @@ func RunCommandLineWithOptions() error {
 				Usage:       "Output encoded. (yaml, json)",
 				Destination: &output,
 			},
+			&cli.StringFlag{
+				Name:        "secrets-dir",
+				Value:       "",
+				Usage:       "Directory to look up Secret values from at install time (default: $NAML_SECRETS_DIR)",
+				Destination: &secretsDirectory,
+			},
+			&cli.StringFlag{
+				Name:        "namespace",
+				Aliases:     []string{"n"},
+				Value:       "",
+				Usage:       "Install, uninstall and output every namespaced object in this namespace. Cluster scoped objects are not changed",
+				Destination: &namespace,
+			},
+			&cli.StringSliceFlag{
+				Name:        "values",
+				Usage:       "Decode a YAML or JSON file into the values of a Configurable app before install and output",
+				Destination: &valuesFiles,
+			},
+			&cli.StringSliceFlag{
+				Name:        "set",
+				Usage:       "Set a value of a Configurable app before install and output. Example: image.tag=v1.2.3",
+				Destination: &valueSets,
+			},
+			&cli.StringFlag{
+				Name:        "profile",
+				Value:       "",
+				Usage:       "Apply the patches of a profile registered by the app, such as dev, staging or prod",
+				Destination: &profileName,
+			},
+			&cli.StringSliceFlag{
+				Name:        "patch",
+				Usage:       "Apply a YAML file of strategic merge patches at install and output time. Each patch targets the object with its kind and name",
+				Destination: &patchFiles,
+			},
+			&cli.StringSliceFlag{
+				Name:        "json-patch",
+				Usage:       "Apply an RFC 6902 JSON patch file to a target at install and output time. Example: Deployment/web=patch.json",
+				Destination: &jsonPatches,
+			},
+			&cli.StringSliceFlag{
+				Name:        "transform",
+				Usage:       "Transform objects at install and output time with a registered transformer. Example: namespace=production, labels=team=web, prefix=dev-",
+				Destination: &transforms,
+			},
+			&cli.StringSliceFlag{
+				Name:        "image-rewrite",
+				Usage:       "Rewrite container images at install and output time. Example: 'docker.io/* => registry.corp/dockerhub/*'",
+				Destination: &imageRewriteRules,
+			},
+			&cli.StringFlag{
+				Name:        "image-rewrite-file",
+				Value:       "",
+				Usage:       "File with one image rewrite rule per line (default: $NAML_IMAGE_REWRITE_FILE)",
+				Destination: &imageRewriteFile,
+			},
 			&cli.StringSliceFlag{
 				Name:        "with",
 				Aliases:     []string{"f", "w"}, // use -f to follow kubectl -f syntax trolol
@@ -166,7 +342,7 @@ func RunCommandLineWithOptions() error {
 			{
 				Name:      "install",
 				Aliases:   []string{"i"},
-				Usage:     "Install a package in Kubernetes",
+				Usage:     "Install a package in Kubernetes. With transforms the objects are created instead of running the Install() of the package",
 				UsageText: "naml install [name]",
 				Action: func(c *cli.Context) error {
 					// ----------------------------------
@@ -207,7 +383,7 @@ func RunCommandLineWithOptions() error {
 			{
 				Name:      "uninstall",
 				Aliases:   []string{"u"},
-				Usage:     "Uninstall a package in Kubernetes",
+				Usage:     "Uninstall a package in Kubernetes. With transforms the objects are deleted instead of running the Uninstall() of the package",
 				UsageText: "naml uninstall [name]",
 				Action: func(c *cli.Context) error {
 					// ----------------------------------
@@ -258,6 +434,42 @@ func RunCommandLineWithOptions() error {
 						Usage:       "Toggle library mode for codify output Go code. When true will output library code instead of a main package.",
 						Destination: &library,
 					},
+					&cli.BoolFlag{
+						Name:        "functions",
+						Value:       false,
+						Usage:       "Toggle function mode for codify output Go code. When true every object will be generated in its own constructor function.",
+						Destination: &functions,
+					},
+					&cli.BoolFlag{
+						Name:        "secret-lookup",
+						Value:       false,
+						Usage:       "Toggle secret lookup mode for codify output Go code. When true Secret values will be looked up at install time instead of written to the code.",
+						Destination: &secretLookup,
+					},
+					&cli.BoolFlag{
+						Name:        "hoist",
+						Value:       false,
+						Usage:       "Define values that are repeated across objects (such as labels and selectors) once. Structs become package level variables, maps and slices become functions that return a new copy.",
+						Destination: &hoist,
+					},
+					&cli.BoolFlag{
+						Name:        "verify",
+						Value:       false,
+						Usage:       "Compile and run the generated code, and compare the output with the input YAML. Exits non-zero if any object is lost or changed.",
+						Destination: &verify,
+					},
+					&cli.StringFlag{
+						Name:        "template-dir",
+						Value:       "",
+						Usage:       "Directory of templates that override the built in templates. Example: deployment.install.tpl",
+						Destination: &codifyValues.TemplateDir,
+					},
+					&cli.BoolFlag{
+						Name:        "template-schema",
+						Value:       false,
+						Usage:       "Print the JSON schema of the variables available in every template and exit.",
+						Destination: &templateSchema,
+					},
 					&cli.StringFlag{
 						Name:        "package-name",
 						Value:       "library",
@@ -266,14 +478,14 @@ func RunCommandLineWithOptions() error {
 					},
 					&cli.StringFlag{
 						Name:        "author-name",
-						Value:       "Kris Nóva",
-						Usage:       "Name for the copyright header",
+						Value:       "",
+						Usage:       "Name for the copyright header (default: git user.name)",
 						Destination: &codifyValues.AuthorName,
 					},
 					&cli.StringFlag{
 						Name:        "author-email",
-						Value:       "<kris@nivenly.com>",
-						Usage:       "Email for the copyright header",
+						Value:       "",
+						Usage:       "Email for the copyright header (default: git user.email)",
 						Destination: &codifyValues.AuthorEmail,
 					},
 					&cli.StringFlag{
@@ -301,10 +513,38 @@ func RunCommandLineWithOptions() error {
 					codifyValues.AppNameLower = strings.ToLower(codifyAppNameRaw)
 					codifyValues.AppNameTitle = strings.Title(codifyValues.AppNameLower)
 					codifyValues.LibraryMode = library
+					codifyValues.FunctionMode = functions
+					codifyValues.SecretLookup = secretLookup
+					codifyValues.Hoist = hoist
 					if codifyValues.LibraryMode {
 						codifyValues.PackageName = packageName
 					}
 
+					if templateSchema {
+						schema, err := TemplateSchema()
+						if err != nil {
+							return err
+						}
+						fmt.Println(string(schema))
+						return nil
+					}
+
+					// The copyright header defaults to the git user, which is
+					// only looked up here so other commands do not run git
+					if !c.IsSet("author-name") || !c.IsSet("author-email") {
+						authorName, authorEmail := gitAuthor()
+						if !c.IsSet("author-name") {
+							codifyValues.AuthorName = authorName
+						}
+						if !c.IsSet("author-email") {
+							codifyValues.AuthorEmail = authorEmail
+						}
+					}
+
+					if verify {
+						return codifyVerify(os.Stdin, codifyValues)
+					}
+
 					cbytes, err := Codify(os.Stdin, codifyValues)
 					if err != nil {
 						if len(cbytes) > 0 {
@@ -416,53 +656,334 @@ func RunCommandLineWithOptions() error {
 			{
 				Name:      "output",
 				Aliases:   []string{"o"},
-				Usage:     "Output embedded applications. (yaml, json)",
-				UsageText: "naml output [name] -o yaml",
+				Usage:     "Output embedded applications. (yaml, json, jsonl, helm)",
+				UsageText: "naml output [name] -o yaml [--dir ./rendered]",
 				Action: func(c *cli.Context) error {
+					// Keep stdout clean for the encoded output
+					logger.Writer = os.Stderr
 					logger.Warning("⚠ naml output alpha feature ⚠")
 					logger.Warning("if this is a feature you plan on using please make your use case known in the issue tracker")
 					logger.Warning("⚠ naml output alpha feature ⚠")
-					return outputFunc(output, c)
+					if outputDir != "" {
+						return outputDirFunc(output, outputDir, c)
+					}
+					return outputFunc(output, outputList, c)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Value:       "yaml",
+						Usage:       "output format (yaml, json, jsonl, helm)",
+						Destination: &output,
+					},
+					&cli.BoolFlag{
+						Name:        "list",
+						Value:       false,
+						Usage:       "Wrap the objects in a single v1.List.",
+						Destination: &outputList,
+					},
+					&cli.StringFlag{
+						Name:        "dir",
+						Value:       "",
+						Usage:       "Write one file per object and a kustomization.yaml to this directory instead of stdout.",
+						Destination: &outputDir,
+					},
+				},
+			},
+
+			// ********************************************************
+			// [ IMAGES ]
+			// ********************************************************
+
+			{
+				Name:      "images",
+				Usage:     "List the container images of embedded applications",
+				UsageText: "naml images [name] -o json --fail-on-latest",
+				Action: func(c *cli.Context) error {
+					var names []string
+					if c.Args().Len() == 1 {
+						names = append(names, c.Args().First())
+					} else {
+						for name := range Registry() {
+							names = append(names, name)
+						}
+						sort.Strings(names)
+					}
+					return imagesFunc(names, output, failOnLatest)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Value:       "table",
+						Usage:       "output format (table, json, jsonl)",
+						Destination: &output,
+					},
+					&cli.BoolFlag{
+						Name:        "fail-on-latest",
+						Value:       false,
+						Usage:       "Exit non-zero if any image uses the latest tag, or has no tag.",
+						Destination: &failOnLatest,
+					},
+				},
+			},
+
+			// ********************************************************
+			// [ VALIDATE ]
+			// ********************************************************
+
+			{
+				Name:      "validate",
+				Usage:     "Validate embedded applications against the Kubernetes OpenAPI schema",
+				UsageText: "naml validate [name]",
+				Action: func(c *cli.Context) error {
+					arguments := c.Args()
+					if arguments.Len() == 1 {
+						return validateFunc(arguments.First())
+					}
+					var names []string
+					for name := range Registry() {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					var failed []string
+					err := transformApps(names, func(name string) error {
+						err := validateFunc(name)
+						if err != nil {
+							failed = append(failed, name)
+						}
+						return nil
+					})
+					if err != nil {
+						return err
+					}
+					if len(failed) > 0 {
+						return fmt.Errorf("validation failed: %s", strings.Join(failed, ", "))
+					}
+					return nil
+				},
+			},
+
+			// ********************************************************
+			// [ RBAC ]
+			// ********************************************************
+
+			{
+				Name:      "rbac",
+				Usage:     "Output the minimal RBAC that a ServiceAccount needs to install and uninstall an application",
+				UsageText: "naml rbac [name] --service-account deployer --service-account-namespace ci",
+				Action: func(c *cli.Context) error {
+					arguments := c.Args()
+					if arguments.Len() == 1 {
+						return rbacFunc(arguments.First(), rbacServiceAccount, rbacNamespace, output)
+					}
+					if len(Registry()) == 1 {
+						for name := range Registry() {
+							return rbacFunc(name, rbacServiceAccount, rbacNamespace, output)
+						}
+					}
+					Banner()
+					cli.ShowCommandHelp(c, "rbac")
+					List()
+					return nil
 				},
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:        "output",
 						Aliases:     []string{"o"},
 						Value:       "yaml",
-						Usage:       "output format",
+						Usage:       "output format (yaml, json, jsonl)",
 						Destination: &output,
 					},
+					&cli.StringFlag{
+						Name:        "service-account",
+						Value:       "",
+						Usage:       "Name of the ServiceAccount, roles and bindings (default: naml-<app>)",
+						Destination: &rbacServiceAccount,
+					},
+					&cli.StringFlag{
+						Name:        "service-account-namespace",
+						Value:       "default",
+						Usage:       "Namespace of the ServiceAccount, such as the namespace of the Job that runs naml",
+						Destination: &rbacNamespace,
+					},
 				},
 			},
 		},
 	}
+
+	// Defaults from .naml.yaml in the home and working directory
+	config, err := LoadConfig(ConfigPaths()...)
+	if err != nil {
+		return err
+	}
+	err = config.Apply(app, codifyValues)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", ConfigFile, err)
+	}
 	return app.Run(os.Args)
 }
 
-func outputFunc(encoding string, c *cli.Context) error {
-	var o OutputEncoding
-	encoding = strings.ToLower(encoding)
-	if encoding == "json" {
-		o = OutputJSON
+// codifyVerify will codify the input, print the generated code,
+// and verify the generated code against the input.
+func codifyVerify(input io.Reader, values *CodifyValues) error {
+	ibytes, err := ReaderToBytes(input)
+	if err != nil {
+		return err
+	}
+	cbytes, report, err := CodifyVerify(ibytes, values)
+	if len(cbytes) > 0 {
+		fmt.Println(string(cbytes))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error during codify: %v", err)
+		return err
+	}
+	fmt.Fprint(os.Stderr, report.String())
+	if report.Failed() {
+		return fmt.Errorf("verify failed: generated code does not match input YAML")
+	}
+	return nil
+}
+
+func outputFunc(encoding string, list bool, c *cli.Context) error {
+	o, err := ParseOutputEncoding(encoding)
+	if err != nil {
+		return err
 	}
-	if encoding == "yaml" {
-		o = OutputYAML
+	opts := &RenderOptions{
+		Encoding: o,
+		List:     list,
 	}
 
 	arguments := c.Args()
 
 	// No specific apps were passed
 	if arguments.Len() != 1 {
-		for name, _ := range Registry() {
-			err := RunOutput(name, o)
-			if err != nil {
-				return fmt.Errorf("unable to run in runtime mode: %v", err)
-			}
+		var names []string
+		for name := range Registry() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		err := RunRenderApps(os.Stdout, names, opts)
+		if err != nil {
+			return fmt.Errorf("unable to run in runtime mode: %v", err)
 		}
 		return nil
 	}
 	appName := arguments.First()
-	err := RunOutput(appName, o)
+	err = RunRender(os.Stdout, appName, opts)
+	if err != nil {
+		return fmt.Errorf("unable to run in runtime mode: %v", err)
+	}
+	return nil
+}
+
+// gitAuthor will return the name and email of the git user, or empty
+// strings when git or the user is not configured.
+func gitAuthor() (string, string) {
+	name := gitConfig("user.name")
+	email := gitConfig("user.email")
+	if email != "" {
+		email = fmt.Sprintf("<%s>", email)
+	}
+	return name, email
+}
+
+// gitConfig will return a value of the git config, or an empty string.
+func gitConfig(key string) string {
+	out, err := exec.Command("git", "config", "--get", key).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// imagesFunc will print the images of every app in names.
+func imagesFunc(names []string, encoding string, failOnLatest bool) error {
+	o := OutputYAML
+	if encoding != "table" {
+		var err error
+		o, err = ParseOutputEncoding(encoding)
+		if err != nil {
+			return err
+		}
+	}
+	var images []*Image
+	err := transformApps(names, func(name string) error {
+		appImages, err := RunImages(name)
+		if err != nil {
+			return err
+		}
+		images = append(images, appImages...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = PrintImages(os.Stdout, images, o)
+	if err != nil {
+		return err
+	}
+	if latest := LatestImages(images); failOnLatest && len(latest) > 0 {
+		return fmt.Errorf("images with the latest tag: %s", strings.Join(latest, ", "))
+	}
+	return nil
+}
+
+// rbacFunc will print the RBAC of a single app.
+func rbacFunc(appName, serviceAccount, namespace, encoding string) error {
+	o, err := ParseOutputEncoding(encoding)
+	if err != nil {
+		return err
+	}
+	if o == OutputHelm {
+		return fmt.Errorf("invalid output format for rbac: %s", encoding)
+	}
+	return RunRBAC(os.Stdout, appName, serviceAccount, namespace, o)
+}
+
+// validateFunc will validate a single app and print the report.
+func validateFunc(appName string) error {
+	report, err := RunValidate(appName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%s] Kubernetes %s\n", appName, OpenAPIVersion)
+	fmt.Print(report.String())
+	if report.Failed() {
+		return fmt.Errorf("validation failed: %s", appName)
+	}
+	return nil
+}
+
+// outputDirFunc will write the output to a directory. If more than
+// one app is output every app is written to its own sub directory.
+func outputDirFunc(encoding, dir string, c *cli.Context) error {
+	o, err := ParseOutputEncoding(encoding)
+	if err != nil {
+		return err
+	}
+
+	arguments := c.Args()
+
+	// No specific apps were passed
+	if arguments.Len() != 1 {
+		var names []string
+		for name := range Registry() {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		err := transformApps(names, func(name string) error {
+			return RunOutputDir(name, o, filepath.Join(dir, name))
+		})
+		if err != nil {
+			return fmt.Errorf("unable to run in runtime mode: %v", err)
+		}
+		return nil
+	}
+	err = RunOutputDir(arguments.First(), o, dir)
 	if err != nil {
 		return fmt.Errorf("unable to run in runtime mode: %v", err)
 	}
@@ -482,22 +1003,12 @@ func AllInit(kubeConfigPath string, verbose bool, with []string) error {
 	}
 
 	// [ Kubeconfig System ]
-	// 1. Check if environmental variable is set
-	// 2. Default to the --kubeconfig flag
-	// 3. Follow the logic in the Clientcmd (path, masterURL, inCluster, default)
-
-	// Format "~" in command line string
-	kubeConfigPath = strings.ReplaceAll(kubeConfigPath, "~", homedir.HomeDir())
-
-	// Here be dragons
-	// We probably need an entire fucking client package, but for now
-	// this will get us to 1.0.0
-	envVarValue := os.Getenv(KubeconfigEnvironmentalVariable)
-	if envVarValue == "" {
-		kubeConfigPathValue = kubeConfigPath
-	} else {
-		kubeConfigPathValue = envVarValue
-	}
+	// Follow the loading rules of kubectl
+	// 1. The --kubeconfig flag is the only kubeconfig when set
+	// 2. Merge every kubeconfig in the KUBECONFIG environmental variable
+	// 3. Default to ~/.kube/config
+	kubeConfigPathValue = ExpandHome(kubeConfigPath)
+	cachedClient = nil
 	logger.Debug("Kubeconfig Value: %s", kubeConfigPathValue)
 
 	return nil
@@ -505,6 +1016,11 @@ func AllInit(kubeConfigPath string, verbose bool, with []string) error {
 
 // Install is used to install an application in Kubernetes
 func Install(app Deployable) error {
+	err := Configure(app)
+	if err != nil {
+		return err
+	}
+
 	// Only grab a client if we are running in this instance!
 	client, err := Client()
 	if err != nil {
@@ -512,7 +1028,11 @@ func Install(app Deployable) error {
 	}
 
 	// Install
-	err = app.Install(client)
+	if Transforming() {
+		err = InstallObjects(client, app)
+	} else {
+		err = app.Install(client)
+	}
 	if err != nil {
 		return err
 	}
@@ -533,8 +1053,15 @@ func List() {
 		fmt.Printf("[%s]\n", app.Meta().Name)
 		fmt.Printf("  Description : %s\n", app.Meta().Description)
 		fmt.Printf("  Version     : %s\n", app.Meta().ResourceVersion)
+		if names := Profiles(app); len(names) > 0 {
+			fmt.Printf("  Profiles    : %s\n", strings.Join(names, ", "))
+		}
 		app.Install(nil)
-		for _, obj := range app.Objects() {
+		objs, err := AppObjects(app)
+		if err != nil {
+			logger.Warning("%v", err)
+		}
+		for _, obj := range objs {
 			_, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
 			fmt.Printf("    > %s\n", kind)
 		}
@@ -544,13 +1071,21 @@ func List() {
 
 // Uninstall is used to uninstall an application in Kubernetes
 func Uninstall(app Deployable) error {
+	err := Configure(app)
+	if err != nil {
+		return err
+	}
 	client, err := Client()
 	if err != nil {
 		return err
 	}
 
 	// Uninstall
-	err = app.Uninstall(client)
+	if Transforming() {
+		err = UninstallObjects(client, app)
+	} else {
+		err = app.Uninstall(client)
+	}
 	if err != nil {
 		return err
 	}
@@ -563,8 +1098,17 @@ func Uninstall(app Deployable) error {
 	return nil
 }
 
+// PrintObjects will print the kind and version of every object in the application,
+// after every transform when transforming.
 func PrintObjects(app Deployable) {
-	for _, obj := range app.Objects() {
+	objs, err := AppObjects(app)
+	if Transforming() {
+		objs, err = TransformedObjects(app)
+	}
+	if err != nil {
+		logger.Warning("%v", err)
+	}
+	for _, obj := range objs {
 		kind := obj.GetObjectKind()
 		s := kind.GroupVersionKind()
 		fmt.Printf("  %s %s\n", s.Kind, s.Version)
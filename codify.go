@@ -31,14 +31,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"text/template"
 
 	policyv1 "k8s.io/api/policy/v1beta1"
 
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 
-	"k8s.io/apimachinery/pkg/runtime"
-
 	"github.com/fatih/color"
 
 	"github.com/kris-nova/logger"
@@ -72,31 +69,43 @@ const (
 // into the .naml files in /src. These values
 // are what will be created in the output.
 type CodifyValues struct {
-	LibraryMode   bool
-	AuthorName    string
-	AuthorEmail   string
-	CopyrightYear string
-	AppNameTitle  string
-	AppNameLower  string
-	Description   string
-	Version       string
-	Install       string
-	Uninstall     string
-	Packages      string
-	PackageName   string
+	LibraryMode   bool   `description:"True when generating a library package instead of a main package."`
+	FunctionMode  bool   `description:"True when every object is generated in its own constructor function."`
+	SecretLookup  bool   `description:"True when Secret values are looked up at install time."`
+	Hoist         bool   `description:"True when repeated values are defined once as package level variables."`
+	TemplateDir   string `description:"The directory of templates that override the built in templates."`
+	AuthorName    string `description:"The name for the copyright header."`
+	AuthorEmail   string `description:"The email for the copyright header."`
+	CopyrightYear string `description:"The year for the copyright header."`
+	AppNameTitle  string `description:"The application name in title case. Used for the Go type."`
+	AppNameLower  string `description:"The application name in lower case. Used for the Kubernetes name."`
+	Description   string `description:"The description of the application."`
+	Version       string `description:"The version of the application."`
+	Install       string `description:"The Go code for Install(), rendered from every install template."`
+	Uninstall     string `description:"The Go code for Uninstall(), rendered from every uninstall template."`
+	Packages      string `description:"The Go import block for every package the objects depend on."`
+	PackageName   string `description:"The Go package name."`
+	Functions     string `description:"The Go code for every constructor function. Only set in function mode."`
+	Constructors  string `description:"The calls to every constructor function. Only set in function mode."`
+	Secrets       string `description:"The comment that lists every Secret value that is looked up at install time."`
+	SecretLookups string `description:"The Go map of the keys looked up for each Secret at install time. Only set in function mode."`
 }
 
-type CodifyObject interface {
+// CodifyObject is a Kubernetes object that can be codified.
+//
+// Support for new kinds can be added with codify.Register.
+type CodifyObject = codify.Object
 
-	// Install returns the snippet of code that would
-	// traditionally live inside a function. This
-	// will define literally (what it can) a struct
-	// for the object, and pass it to the corresponding
-	// kubernetes library.
-	Install() (string, []string)
+// CodifyConstructor is an optional interface for a CodifyObject
+// that can be generated in its own constructor function.
+//
+// Every object must implement CodifyConstructor in order to
+// codify with CodifyValues.FunctionMode.
+type CodifyConstructor interface {
 
-	// Uninstall is the reverse library call of install.
-	Uninstall() string
+	// Constructor returns the source code for a function
+	// that will return the object.
+	Constructor() (*codify.Constructor, error)
 }
 
 // Codify will take any valid Kubernetes YAML as an io.Reader
@@ -113,45 +122,105 @@ func Codify(input io.Reader, v *CodifyValues) ([]byte, error) {
 
 	var code []byte
 
-	// Setup template with a unique name based on the input
-	tpl := template.New(fmt.Sprintf("%+v%+v", input, v.AppNameLower))
+	// The generated code is built up on the values, so
+	// the same values can be used more than once.
+	v.Install = ""
+	v.Uninstall = ""
+	v.Functions = ""
+	v.Constructors = ""
+	v.Secrets = ""
+	v.SecretLookups = ""
+
+	// Templates in the template directory override the built in templates
+	templates, err := codify.LoadTemplates(v.TemplateDir)
+	if err != nil {
+		return code, err
+	}
+
+	// Function mode uses a constructor for every object instead of
+	// the kind templates, so only the package templates can be overridden
+	if v.FunctionMode {
+		for _, name := range templates.Names() {
+			if name != codify.MainTemplate && name != codify.LibraryTemplate {
+				return code, fmt.Errorf("template %s%s is not supported in function mode", name, codify.TemplateExtension)
+			}
+		}
+	}
 
 	// Create the base file
+	templateName := codify.MainTemplate
 	templateString := FormatMainGo
 	if v.LibraryMode {
+		templateName = codify.LibraryTemplate
 		templateString = FormatLibraryGo
 	}
-	tpl, err := tpl.Parse(templateString)
+	tpl, err := templates.Template(templateName, templateString)
 	if err != nil {
 		return code, fmt.Errorf("unable to create main go tempalte: %v", err)
 	}
 
 	// Find the objects
-	objs, delta, err := ReaderToCodifyObjects(input)
+	ibytes, err := ReaderToBytes(input)
+	if err != nil {
+		return code, err
+	}
+	run := &codifyRun{templates: templates}
+	defer run.done()
+	objs, documentComments, delta, err := codifyDocuments(ibytes, run)
 	if err != nil {
 		return code, fmt.Errorf("unable to parse objects: %v", err)
 	}
 
+	// Keep secret values out of the source code
+	if v.SecretLookup {
+		v.Secrets, err = codifySecretLookup(objs)
+		if err != nil {
+			return code, err
+		}
+		if v.FunctionMode {
+			v.SecretLookups = codifySecretLookups(objs)
+		}
+	}
+
 	// Create map of used packages
 	packages := make(map[string]bool)
 
-	// Append both install and uninstall for every object
-	for _, obj := range objs {
-		// get the install code and packages it depends on
-		install, localPackages := obj.Install()
-
-		// add all packages to the package map
-		for _, pkg := range localPackages {
-			packages[pkg] = true
+	if v.FunctionMode {
+		err = codifyFunctions(objs, documentComments, v, packages)
+		if err != nil {
+			return code, err
 		}
+	} else {
+		// Append both install and uninstall for every object
+		for _, obj := range objs {
+			// get the install code and packages it depends on
+			install, localPackages, err := obj.Install()
+			if err != nil {
+				return code, err
+			}
 
-		// add to install
-		v.Install = fmt.Sprintf("%s\n%s", v.Install, install)
+			// add all packages to the package map
+			for _, pkg := range localPackages {
+				packages[pkg] = true
+			}
 
-		if v.Uninstall == "" {
-			v.Uninstall = obj.Uninstall()
-		} else {
-			v.Uninstall = fmt.Sprintf("%s\n%s", v.Uninstall, obj.Uninstall())
+			// add the YAML comments above the object
+			if lines, ok := documentComments[obj]; ok {
+				install = fmt.Sprintf("\n%s%s", codify.GoComment(lines), strings.TrimLeft(install, "\n"))
+			}
+
+			// add to install
+			v.Install = fmt.Sprintf("%s\n%s", v.Install, install)
+
+			uninstall, err := obj.Uninstall()
+			if err != nil {
+				return code, err
+			}
+			if v.Uninstall == "" {
+				v.Uninstall = uninstall
+			} else {
+				v.Uninstall = fmt.Sprintf("%s\n%s", v.Uninstall, uninstall)
+			}
 		}
 	}
 
@@ -163,7 +232,7 @@ func Codify(input io.Reader, v *CodifyValues) ([]byte, error) {
 	sort.Strings(packagesSlice)
 
 	// define list of import aliases
-	packageAliases := codify.KubernetesImportPackageMap
+	packageAliases := codify.ImportPackageMap()
 
 	packagesCode := ""
 	for _, pkg := range packagesSlice {
@@ -186,6 +255,14 @@ func Codify(input io.Reader, v *CodifyValues) ([]byte, error) {
 	// Grab the source code in []byte form
 	src := buf.Bytes()
 
+	// Share repeated values between objects
+	if v.Hoist {
+		src, err = hoist(src)
+		if err != nil {
+			return code, fmt.Errorf("unable to hoist shared values: %v", err)
+		}
+	}
+
 	// Go fmt!
 	var fmtBytes []byte
 	if codifyGoFormat {
@@ -220,6 +297,123 @@ func Codify(input io.Reader, v *CodifyValues) ([]byte, error) {
 	return fmtBytes, nil
 }
 
+// codifySecretLookup will enable install time lookups for every Secret,
+// and will return the comment header that lists every key that will be looked up.
+//
+// An error is returned if two keys would be looked up from the same environmental variable.
+func codifySecretLookup(objs []CodifyObject) (string, error) {
+	var secrets string
+	lookups := make(map[string][]string)
+	for _, obj := range objs {
+		secret, ok := obj.(*codify.Secret)
+		if !ok {
+			continue
+		}
+		secret.EnableLookup()
+		if len(secret.LookupKeys) == 0 {
+			continue
+		}
+		meta := secret.KubeObject.ObjectMeta
+		secretKey := SecretValueKey(meta.Namespace, meta.Name, "")
+		lookups[secretKey] = append(lookups[secretKey], secret.LookupKeys...)
+		secrets = fmt.Sprintf("%s//\n//   Secret: %s\n", secrets, secretKey)
+		for _, key := range secret.LookupKeys {
+			secrets = fmt.Sprintf("%s//     %s: $%s\n", secrets, key, SecretEnvironmentalVariable(meta.Namespace, meta.Name, key))
+		}
+	}
+	err := CheckSecretLookups(lookups)
+	if err != nil {
+		return "", err
+	}
+	if secrets == "" {
+		return "", nil
+	}
+	header := `// Secret Values
+//
+// Secret values are not defined in this source code. Each value is looked up at
+// install time with naml.LookupSecretData() from the first of the following:
+//
+//   1. Values set in Go with naml.SetSecretValues()
+//   2. The environmental variable listed below
+//   3. The file <key> in the <secret> directory of --secrets-dir or $NAML_SECRETS_DIR
+//
+// Install will fail if a value is missing.
+`
+	return header + secrets, nil
+}
+
+// codifySecretLookups will return the Go map of the keys to look up for
+// every Secret, which function mode looks up in Install() instead of in
+// the constructor of each Secret.
+func codifySecretLookups(objs []CodifyObject) string {
+	var lookups string
+	for _, obj := range objs {
+		secret, ok := obj.(*codify.Secret)
+		if !ok || len(secret.LookupKeys) == 0 {
+			continue
+		}
+		var keys []string
+		for _, key := range secret.LookupKeys {
+			keys = append(keys, fmt.Sprintf("%q", key))
+		}
+		meta := secret.KubeObject.ObjectMeta
+		lookups = fmt.Sprintf("%s\t\t%q: {%s},\n", lookups, SecretValueKey(meta.Namespace, meta.Name, ""), strings.Join(keys, ", "))
+	}
+	if lookups == "" {
+		return ""
+	}
+	return fmt.Sprintf("map[string][]string{\n%s\t}", lookups)
+}
+
+// codifyFunctions will generate one constructor function for every object,
+// and the list of constructor calls used in Objects().
+//
+// The packages used by each constructor are added to the packages map.
+func codifyFunctions(objs []CodifyObject, documentComments map[CodifyObject][]string, v *CodifyValues, packages map[string]bool) error {
+	var constructors []*codify.Constructor
+	reserved := make(map[string]bool)
+	for _, obj := range objs {
+		c, ok := obj.(CodifyConstructor)
+		if !ok {
+			return fmt.Errorf("unable to codify %T in function mode: missing Constructor()", obj)
+		}
+		constructor, err := c.Constructor()
+		if err != nil {
+			return fmt.Errorf("unable to codify constructor: %v", err)
+		}
+		constructors = append(constructors, constructor)
+		reserved[constructor.Name] = true
+	}
+
+	used := make(map[string]bool)
+	for i, constructor := range constructors {
+		obj := objs[i]
+		for _, pkg := range constructor.Packages {
+			packages[pkg] = true
+		}
+
+		// Objects with the same name and kind (in different namespaces)
+		// will need unique function names that do not collide with the
+		// name of any other constructor.
+		name := constructor.Name
+		for n := 2; used[name]; n++ {
+			candidate := fmt.Sprintf("%s%d", constructor.Name, n)
+			if !reserved[candidate] {
+				name = candidate
+			}
+		}
+		used[name] = true
+		v.Functions = fmt.Sprintf("%s\n%s", v.Functions, codify.GoComment(documentComments[obj]))
+		if constructor.Mutate == "" {
+			v.Functions = fmt.Sprintf("%sfunc (x *%s) %s() %s {\n\treturn %s\n}\n", v.Functions, v.AppNameTitle, name, constructor.Type, constructor.Source)
+		} else {
+			v.Functions = fmt.Sprintf("%sfunc (x *%s) %s() %s {\n\tobj := %s\n\t%s\n\treturn obj\n}\n", v.Functions, v.AppNameTitle, name, constructor.Type, constructor.Source, constructor.Mutate)
+		}
+		v.Constructors = fmt.Sprintf("%sx.%s(),\n", v.Constructors, name)
+	}
+	return nil
+}
+
 // ReaderToBytes is basically a wrapper for ReadAll, however
 // we add in some specific error language for stdin.
 func ReaderToBytes(input io.Reader) ([]byte, error) {
@@ -240,21 +434,72 @@ func ReaderToBytes(input io.Reader) ([]byte, error) {
 // it is unable to Codify.
 // If the delta is greater than 0, that means we have encountered a loss.
 func ReaderToCodifyObjects(input io.Reader) ([]CodifyObject, int, error) {
-	var objects []CodifyObject
 	ibytes, err := ReaderToBytes(input)
 	if err != nil {
-		return objects, -1, err
-	}
-	clean := string(cleanRaw(ibytes))
-	yamls := strings.Split(clean, YAMLDelimiter)
-	// We support more than one "YAML" per the delimiter
-	// So we need to deal in sets.
-	d := len(yamls)
-	for _, yaml := range yamls {
-		raw := []byte(yaml)
-		cObjects, err := toCodify(raw)
+		return nil, -1, err
+	}
+	objects, _, delta, err := codifyDocuments(ibytes, nil)
+	return objects, delta, err
+}
+
+// codifyRun is the state of a single call to Codify.
+//
+// The comments and templates for every object decoded in the run are
+// removed when the run is done, so they are not kept after Codify returns
+// and are not shared with any other call to Codify.
+type codifyRun struct {
+	templates   *codify.Templates
+	kubeobjects []interface{}
+}
+
+// set will set the comments and templates for a decoded kubeobject.
+// A nil run will not set anything.
+func (r *codifyRun) set(kubeobject interface{}, comments *codify.Comments) {
+	if r == nil {
+		return
+	}
+	codify.SetComments(kubeobject, comments)
+	codify.SetTemplates(kubeobject, r.templates)
+	r.kubeobjects = append(r.kubeobjects, kubeobject)
+}
+
+// done will remove the comments and templates for every object decoded in the run.
+func (r *codifyRun) done() {
+	for _, kubeobject := range r.kubeobjects {
+		codify.SetComments(kubeobject, nil)
+		codify.SetTemplates(kubeobject, nil)
+	}
+	r.kubeobjects = nil
+}
+
+// codifyDocuments will convert raw YAML to naml compatible Go objects.
+//
+// The YAML comments are kept for each document. Field comments are set on
+// the run, and the document comments are returned for each object.
+// Comments in a document without any objects are carried to the next document.
+func codifyDocuments(raw []byte, run *codifyRun) ([]CodifyObject, map[CodifyObject][]string, int, error) {
+	var objects []CodifyObject
+	documentComments := make(map[CodifyObject][]string)
+	var carried []string
+	d := 0
+	for _, document := range splitDocuments(raw) {
+		comments := codify.ParseComments(document)
+		clean := cleanRaw(document)
+		if len(clean) <= 1 {
+			carried = append(carried, comments.Document...)
+			continue
+		}
+		d++
+		cObjects, err := toCodify(clean, comments, run)
 		if err != nil {
-			return objects, -1, fmt.Errorf("unable to codify: %v", err)
+			return objects, documentComments, -1, fmt.Errorf("unable to codify: %v", err)
+		}
+		if len(cObjects) > 0 {
+			lines := append(carried, comments.Document...)
+			if len(lines) > 0 {
+				documentComments[cObjects[0]] = lines
+			}
+			carried = nil
 		}
 		// Merge the items
 		for _, c := range cObjects {
@@ -262,7 +507,23 @@ func ReaderToCodifyObjects(input io.Reader) ([]CodifyObject, int, error) {
 		}
 	}
 	c := len(objects)
-	return objects, d - c, nil
+	return objects, documentComments, d - c, nil
+}
+
+// splitDocuments will split raw YAML on the YAMLDelimiter and
+// keep the comments in each document.
+func splitDocuments(raw []byte) [][]byte {
+	var documents [][]byte
+	var lines []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line == strings.TrimSpace(YAMLDelimiter) {
+			documents = append(documents, []byte(strings.Join(lines, "\n")))
+			lines = nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return append(documents, []byte(strings.Join(lines, "\n")))
 }
 
 // cleanRaw will clean raw yaml
@@ -291,15 +552,31 @@ func cleanRaw(raw []byte) []byte {
 	return []byte(cleanedRawString)
 }
 
-func toCodify(raw []byte) ([]CodifyObject, error) {
+// toCodify will convert a single YAML document to CodifyObjects.
+//
+// The comments are optional, and will be set on the run for the decoded object.
+func toCodify(raw []byte, comments *codify.Comments, run *codifyRun) ([]CodifyObject, error) {
 	var objects []CodifyObject
 	if len(raw) <= 1 {
 		return objects, nil
 	}
 
+	// Registered codifiers are checked first
+	decoded, codifier, err := codify.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to deserialize in codify: %v\n\nraw:\n\n%s", err, string(raw))
+	}
+	if codifier != nil {
+		run.set(decoded, comments)
+		obj, err := codifier.Factory(decoded)
+		if err != nil {
+			return nil, fmt.Errorf("unable to codify %v: %v", codifier.GroupVersionKind, err)
+		}
+		return append(objects, obj), nil
+	}
+
 	serializer := scheme.Codecs.UniversalDeserializer()
-	var decoded runtime.Object
-	decoded, _, err := serializer.Decode([]byte(raw), nil, nil)
+	decoded, _, err = serializer.Decode([]byte(raw), nil, nil)
 	if err != nil {
 		// Here we try CRDs
 		decoded, _, err = serializer.Decode([]byte(raw), nil, &apiextensionsv1.CustomResourceDefinition{})
@@ -307,6 +584,7 @@ func toCodify(raw []byte) ([]CodifyObject, error) {
 			return nil, fmt.Errorf("trying CRD: unable to deserialize in codify: %v\n\nraw:\n\n%s", err, string(raw))
 		}
 	}
+	run.set(decoded, comments)
 
 	// -------------------------------------------------------------------
 	// [NAML Type Switch]
@@ -323,7 +601,7 @@ func toCodify(raw []byte) ([]CodifyObject, error) {
 		// But we error each time and just
 		// base the error from the inner system.
 		for _, item := range x.Items {
-			cObjects, err := toCodify(item.Raw)
+			cObjects, err := toCodify(item.Raw, nil, run)
 			if err != nil {
 				return objects, err
 			}
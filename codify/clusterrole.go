@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	rbacv1 "k8s.io/api/rbac/v1"
 )
@@ -46,7 +41,7 @@ func NewClusterRole(obj *rbacv1.ClusterRole) *ClusterRole {
 	}
 }
 
-func (k ClusterRole) Install() (string, []string) {
+func (k ClusterRole) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
@@ -54,8 +49,8 @@ func (k ClusterRole) Install() (string, []string) {
 	l := c.Source
 	packages := c.Packages
 
-	install := fmt.Sprintf(`
-	{{ .GoName }}ClusterRole := %s
+	install := `
+	{{ .GoName }}ClusterRole := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}ClusterRole)
 	
 	if client != nil {
@@ -64,20 +59,16 @@ func (k ClusterRole) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("clusterrole.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "rbacv1"), packages
+	return alias(install, "rbacv1"), packages, nil
 }
 
-func (k ClusterRole) Uninstall() string {
+func (k ClusterRole) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.RbacV1().ClusterRoles().Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -87,12 +78,9 @@ func (k ClusterRole) Uninstall() string {
 	}
  `
 
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("clusterrole.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k ClusterRole) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"ClusterRole", "rbacv1")
 }
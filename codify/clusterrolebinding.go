@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	rbacv1 "k8s.io/api/rbac/v1"
 )
@@ -46,15 +41,15 @@ func NewClusterRoleBinding(obj *rbacv1.ClusterRoleBinding) *ClusterRoleBinding {
 	}
 }
 
-func (k ClusterRoleBinding) Install() (string, []string) {
+func (k ClusterRoleBinding) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}ClusterRoleBinding := %s
+	install := `
+	{{ .GoName }}ClusterRoleBinding := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}ClusterRoleBinding)
 	
 	if client != nil {
@@ -63,20 +58,16 @@ func (k ClusterRoleBinding) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("clusterrolebinding.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "rbacv1"), packages
+	return alias(install, "rbacv1"), packages, nil
 }
 
-func (k ClusterRoleBinding) Uninstall() string {
+func (k ClusterRoleBinding) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.RbacV1().ClusterRoleBindings().Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -85,12 +76,9 @@ func (k ClusterRoleBinding) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("clusterrolebinding.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k ClusterRoleBinding) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"ClusterRoleBinding", "rbacv1")
 }
@@ -25,9 +25,12 @@ package codify
 
 import (
 	"fmt"
+	"go/scanner"
+	"go/token"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/hexops/valast"
 
@@ -127,6 +130,35 @@ var (
 	}
 )
 
+// StrippedFields are the fields that codify will intentionally not carry
+// into the generated code. Each field is a dot separated JSON path, and
+// "*" will match every item in a list.
+//
+// Fields under the "" key are stripped from every kind.
+var StrippedFields = map[string][]string{
+	"": {
+		"status",
+		"metadata.creationTimestamp",
+		"metadata.deletionTimestamp",
+		"metadata.selfLink",
+	},
+	"Deployment": {
+		"spec.template.spec.containers.*.resources",
+		"spec.template.spec.initContainers.*.resources",
+	},
+	"StatefulSet": {
+		"spec.template.spec.containers.*.resources",
+		"spec.template.spec.initContainers.*.resources",
+	},
+	"DaemonSet": {
+		"spec.template.spec.containers.*.resources",
+		"spec.template.spec.initContainers.*.resources",
+	},
+	"PersistentVolumeClaim": {
+		"spec.resources",
+	},
+}
+
 // cleanObjectMeta helps us get rid of things like timestamps
 // by only "opting in" to certain fields.
 func cleanObjectMeta(m metav1.ObjectMeta) metav1.ObjectMeta {
@@ -148,45 +180,68 @@ func cleanObjectMeta(m metav1.ObjectMeta) metav1.ObjectMeta {
 }
 
 // alias will do it's best to manage package aliases in the source code
+//
+// Only package identifiers are replaced. String literals (such as an
+// apiVersion of "apps/v1" or an image tag) and comments are left alone.
 func alias(generated, defaultalias string) string {
-	aliased := generated
+	src := []byte(generated)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	// Errors are ignored, we only care about the identifiers
+	s.Init(file, src, func(pos token.Position, msg string) {}, 0)
+
+	var aliased strings.Builder
+	last := 0
+	var prevPos token.Pos
+	var prevLit string
+	var prevTok token.Token
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		// Look for "v1." or "v1beta1." and replace the package identifier
+		if prevTok == token.IDENT && strings.HasPrefix(prevLit, "v1") && tok == token.PERIOD {
+			offset := file.Offset(prevPos)
+			aliased.Write(src[last:offset])
+			last = offset + len(prevLit)
+			pkg := defaultalias + strings.TrimPrefix(prevLit, "v1")
+			if prevLit == "v1" {
+				_, _, name := s.Scan()
+				pkg = aliasType(name, defaultalias)
+			}
+			aliased.WriteString(pkg)
+		}
+		prevPos, prevTok, prevLit = pos, tok, lit
+	}
+	aliased.Write(src[last:])
+	return aliased.String()
+}
 
-	// Each object can pass in a "default" to use if we do not have it defined above.
-	aliased = strings.ReplaceAll(aliased, "v1", defaultalias)
+// aliasType will return the package alias for a v1 type name
+func aliasType(name, defaultalias string) string {
 	for _, t := range AppsV1Types {
-		if t == "" {
-			continue
+		if t != "" && strings.HasPrefix(name, t) {
+			return "appsv1"
 		}
-		aliased = strings.ReplaceAll(aliased,
-			fmt.Sprintf("%s.%s", defaultalias, t),
-			fmt.Sprintf("appsv1.%s", t))
 	}
 	for _, t := range MetaV1Types {
-		if t == "" {
-			continue
+		if t != "" && strings.HasPrefix(name, t) {
+			return "metav1"
 		}
-		aliased = strings.ReplaceAll(aliased,
-			fmt.Sprintf("%s.%s", defaultalias, t),
-			fmt.Sprintf("metav1.%s", t))
 	}
 	for _, t := range CoreV1Types {
-		if t == "" {
-			continue
+		if t != "" && strings.HasPrefix(name, t) {
+			return "corev1"
 		}
-		aliased = strings.ReplaceAll(aliased,
-			fmt.Sprintf("%s.%s", defaultalias, t),
-			fmt.Sprintf("corev1.%s", t))
 	}
 	for _, t := range PolicyV1Types {
-		if t == "" {
-			continue
+		if t != "" && strings.HasPrefix(name, t) {
+			return "policyv1beta1" // Note this is different from the others!
 		}
-		aliased = strings.ReplaceAll(aliased,
-			fmt.Sprintf("%s.%s", defaultalias, t),
-			fmt.Sprintf("policyv1beta1.%s", t)) // Note this is different from the others!
-
 	}
-	return aliased
+	return defaultalias
 }
 
 func sanitizeK8sObjectName(name string) string {
@@ -204,9 +259,11 @@ func goName(name string) string {
 // The various components relevant to each conversion are returned in a *Codified
 // object.
 func Literal(kubeobject interface{}) (*Codified, error) {
-	l := valast.String(kubeobject)
+	opt := &valast.Options{PackagePathToName: packagePathToName}
+	l := valast.StringWithOptions(kubeobject, opt)
 	l = cleanValast20(l)
-	r, err := valast.AST(reflect.ValueOf(kubeobject), nil)
+	l = commentLiteral(l, kubeobject)
+	r, err := valast.AST(reflect.ValueOf(kubeobject), opt)
 	if err != nil {
 		return nil, fmt.Errorf("unable to convert to source code: %v", err)
 	}
@@ -218,6 +275,30 @@ func Literal(kubeobject interface{}) (*Codified, error) {
 	}, nil
 }
 
+var (
+	packageNames    = make(map[string]string)
+	packageNamesMtx sync.Mutex
+)
+
+// packagePathToName will look up the name of a Go package once, and
+// cache the name for every literal after.
+//
+// Looking up a package will load the package from disk, which is
+// by far the slowest part of codify.
+func packagePathToName(path string) (string, error) {
+	packageNamesMtx.Lock()
+	defer packageNamesMtx.Unlock()
+	if name, ok := packageNames[path]; ok {
+		return name, nil
+	}
+	name, err := valast.DefaultPackagePathToName(path)
+	if err != nil {
+		return "", err
+	}
+	packageNames[path] = name
+	return name, nil
+}
+
 // Codified is the source code associated with a given Kubernetes object.
 type Codified struct {
 
@@ -242,3 +323,47 @@ func cleanValast20(input string) string {
 	output = strings.ReplaceAll(output, "}}", "},\n}")
 	return output
 }
+
+// Constructor is the source code for a generated function that will
+// return a single Kubernetes object.
+//
+// Constructors are used to codify each object in its own function
+// instead of defining every object inline in Install().
+type Constructor struct {
+
+	// The name of the generated function. Example: exampleDeployment
+	Name string
+
+	// The Go type returned by the generated function. Example: *appsv1.Deployment
+	Type string
+
+	// The Go code to define the object
+	Source string
+
+	// Optional Go code that will mutate the object before it is
+	// returned. The object is available as "obj".
+	Mutate string
+
+	// The packages required to code the object
+	Packages []string
+}
+
+// newConstructor will build a *Constructor for a kubeobject.
+//
+// The defaultalias is the same default alias each object passes to alias().
+func newConstructor(kubeobject interface{}, name, defaultalias string) (*Constructor, error) {
+	c, err := Literal(kubeobject)
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(kubeobject)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return &Constructor{
+		Name:     name,
+		Type:     alias(fmt.Sprintf("*v1.%s", t.Name()), defaultalias),
+		Source:   alias(c.Source, defaultalias),
+		Packages: c.Packages,
+	}, nil
+}
@@ -92,6 +92,25 @@ func TestPolicyV1Beta1(t *testing.T) {
 	}
 }
 
+// TestAliasStringLiterals will check that strings and comments
+// are not aliased, only package identifiers.
+func TestAliasStringLiterals(t *testing.T) {
+	generated := `&v1.Deployment{TypeMeta: v1.TypeMeta{APIVersion: "apps/v1"}, Image: "nginx:v1"} // v1`
+	result := alias(generated, "appsv1")
+	expected := `&appsv1.Deployment{TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1"}, Image: "nginx:v1"} // v1`
+	if result != expected {
+		t.Errorf("unexpected result")
+		t.Errorf("expected: %s", expected)
+		t.Errorf("result:   %s", result)
+	}
+
+	generated = "v1beta1.PodDisruptionBudget"
+	result = alias(generated, "policyv1")
+	if result != "policyv1beta1.PodDisruptionBudget" {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
+
 func TestCleanValast20open(t *testing.T) {
 	input := `something{{`
 	expected := `something{
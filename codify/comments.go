@@ -0,0 +1,305 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package codify
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Comments are the YAML comments for a single Kubernetes object.
+type Comments struct {
+
+	// Document are the comments above the object.
+	Document []string
+
+	// Fields are the comments for each field by YAML path.
+	// Example: spec.template.spec.containers.0.image
+	Fields map[string][]string
+}
+
+var (
+	comments    = make(map[interface{}]*Comments)
+	commentsMtx sync.Mutex
+)
+
+// SetComments will set the comments for a kubeobject. The comments
+// will be written in the Go code by Literal.
+//
+// The kubeobject must be the same pointer that is passed to Literal.
+// Comments are kept until they are set to nil, which naml.Codify
+// does for every object it decodes before it returns.
+func SetComments(kubeobject interface{}, c *Comments) {
+	commentsMtx.Lock()
+	defer commentsMtx.Unlock()
+	if c == nil {
+		delete(comments, kubeobject)
+		return
+	}
+	comments[kubeobject] = c
+}
+
+// GetComments will return the comments for a kubeobject, or nil.
+func GetComments(kubeobject interface{}) *Comments {
+	commentsMtx.Lock()
+	defer commentsMtx.Unlock()
+	return comments[kubeobject]
+}
+
+// ParseComments will parse the comments from a single YAML document.
+//
+// Comments above the first field are considered document comments.
+// Comments that can not be parsed are ignored.
+func ParseComments(raw []byte) *Comments {
+	c := &Comments{
+		Fields: make(map[string][]string),
+	}
+	node := &yaml.Node{}
+	err := yaml.Unmarshal(raw, node)
+	if err != nil {
+		return c
+	}
+	if node.Kind == 0 {
+		// A document with only comments
+		c.Document = commentLines(string(raw))
+		return c
+	}
+	if node.Kind != yaml.DocumentNode {
+		return c
+	}
+	c.Document = append(c.Document, commentLines(node.HeadComment)...)
+	if len(node.Content) == 0 {
+		return c
+	}
+	root := node.Content[0]
+	c.Document = append(c.Document, commentLines(root.HeadComment)...)
+	if root.Kind == yaml.MappingNode && len(root.Content) > 0 {
+		c.Document = append(c.Document, commentLines(root.Content[0].HeadComment)...)
+		root.Content[0].HeadComment = ""
+	}
+	parseFieldComments(root, "", c.Fields)
+	return c
+}
+
+func parseFieldComments(node *yaml.Node, path string, fields map[string][]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := commentPath(path, key.Value)
+			lines := commentLines(key.HeadComment)
+			lines = append(lines, commentLines(key.LineComment)...)
+			lines = append(lines, commentLines(value.LineComment)...)
+			if len(lines) > 0 {
+				fields[keyPath] = append(fields[keyPath], lines...)
+			}
+			parseFieldComments(value, keyPath, fields)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemPath := commentPath(path, strconv.Itoa(i))
+			lines := commentLines(item.HeadComment)
+			if item.Kind == yaml.ScalarNode {
+				lines = append(lines, commentLines(item.LineComment)...)
+			}
+			if len(lines) > 0 {
+				fields[itemPath] = append(fields[itemPath], lines...)
+			}
+			parseFieldComments(item, itemPath, fields)
+		}
+	}
+}
+
+func commentPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// commentLines will convert a YAML comment to lines of text
+// without the leading "#".
+func commentLines(comment string) []string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "#")
+		line = strings.TrimPrefix(line, " ")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// GoComment will return the lines as a Go comment, with a trailing new line.
+func GoComment(lines []string) string {
+	var comment string
+	for _, line := range lines {
+		comment += "// " + line + "\n"
+	}
+	return comment
+}
+
+// commentInsert is text to insert in the source code at an offset.
+type commentInsert struct {
+	offset int
+	text   string
+}
+
+// commentLiteral will add the field comments for the kubeobject
+// to the Go source code for the kubeobject.
+func commentLiteral(source string, kubeobject interface{}) string {
+	c := GetComments(kubeobject)
+	if c == nil || len(c.Fields) == 0 {
+		return source
+	}
+	fset := token.NewFileSet()
+	expr, err := parser.ParseExprFrom(fset, "", source, 0)
+	if err != nil {
+		return source
+	}
+	var inserts []commentInsert
+	commentExpr(fset, source, expr, reflect.TypeOf(kubeobject), "", c.Fields, &inserts)
+	if len(inserts) == 0 {
+		return source
+	}
+	sort.SliceStable(inserts, func(i, j int) bool {
+		return inserts[i].offset > inserts[j].offset
+	})
+	for _, insert := range inserts {
+		source = source[:insert.offset] + insert.text + source[insert.offset:]
+	}
+	return source
+}
+
+// commentExpr will walk the Go expression alongside the Go type and
+// find where each field comment belongs.
+func commentExpr(fset *token.FileSet, source string, expr ast.Expr, t reflect.Type, path string, fields map[string][]string, inserts *[]commentInsert) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return
+	}
+	switch x := expr.(type) {
+	case *ast.UnaryExpr:
+		commentExpr(fset, source, x.X, t, path, fields, inserts)
+	case *ast.CompositeLit:
+		commented := false
+		for i, elt := range x.Elts {
+			var eltPath string
+			var eltType reflect.Type
+			var value ast.Expr = elt
+			switch t.Kind() {
+			case reflect.Struct:
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				key, ok := kv.Key.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				field, ok := t.FieldByName(key.Name)
+				if !ok {
+					continue
+				}
+				eltPath = commentFieldPath(path, field)
+				eltType = field.Type
+				value = kv.Value
+			case reflect.Slice, reflect.Array:
+				eltPath = commentPath(path, strconv.Itoa(i))
+				eltType = t.Elem()
+			case reflect.Map:
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				key, ok := kv.Key.(*ast.BasicLit)
+				if !ok {
+					continue
+				}
+				k, err := strconv.Unquote(key.Value)
+				if err != nil {
+					continue
+				}
+				eltPath = commentPath(path, k)
+				eltType = t.Elem()
+				value = kv.Value
+			default:
+				return
+			}
+			// Inline fields (such as ObjectMeta) have the same path as their parent
+			if lines, ok := fields[eltPath]; ok && eltPath != path {
+				offset := fset.Position(elt.Pos()).Offset
+				text := GoComment(lines)
+				// Start a new line if the field is not the first on its line
+				if strings.TrimSpace(source[strings.LastIndex(source[:offset], "\n")+1:offset]) != "" {
+					text = "\n" + text
+				}
+				*inserts = append(*inserts, commentInsert{offset: offset, text: text})
+				commented = true
+			}
+			commentExpr(fset, source, value, eltType, eltPath, fields, inserts)
+		}
+
+		// A comment before the closing brace would comment it out
+		if commented && len(x.Elts) > 0 {
+			last := fset.Position(x.Elts[len(x.Elts)-1].End()).Offset
+			rbrace := fset.Position(x.Rbrace).Offset
+			between := source[last:rbrace]
+			if !strings.Contains(between, "\n") {
+				text := "\n"
+				if !strings.Contains(between, ",") {
+					text = ",\n"
+				}
+				*inserts = append(*inserts, commentInsert{offset: rbrace, text: text})
+			}
+		}
+	}
+}
+
+// commentFieldPath will return the YAML path for a struct field
+// using the json tag of the field.
+func commentFieldPath(path string, field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	name := strings.Split(tag, ",")[0]
+	if strings.Contains(tag, ",inline") || (field.Anonymous && name == "") {
+		return path
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return commentPath(path, name)
+}
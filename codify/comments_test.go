@@ -0,0 +1,86 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package codify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const commentsTestYAML = `# The example config
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: example # line comment
+data:
+  # why this is here
+  key: value
+`
+
+func TestParseComments(t *testing.T) {
+	c := ParseComments([]byte(commentsTestYAML))
+	if !reflect.DeepEqual(c.Document, []string{"The example config"}) {
+		t.Errorf("unexpected document comments: %v", c.Document)
+	}
+	if !reflect.DeepEqual(c.Fields["metadata.name"], []string{"line comment"}) {
+		t.Errorf("unexpected metadata.name comments: %v", c.Fields["metadata.name"])
+	}
+	if !reflect.DeepEqual(c.Fields["data.key"], []string{"why this is here"}) {
+		t.Errorf("unexpected data.key comments: %v", c.Fields["data.key"])
+	}
+
+	c = ParseComments([]byte("# only a comment\n"))
+	if !reflect.DeepEqual(c.Document, []string{"only a comment"}) {
+		t.Errorf("unexpected comment only document: %v", c.Document)
+	}
+}
+
+func TestLiteralComments(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "example"},
+		Data:       map[string]string{"key": "value"},
+	}
+	SetComments(cm, ParseComments([]byte(commentsTestYAML)))
+	defer SetComments(cm, nil)
+	c, err := Literal(cm)
+	if err != nil {
+		t.Fatalf("unable to codify: %v", err)
+	}
+	for _, expected := range []string{
+		"// line comment\n",
+		"// why this is here\n",
+	} {
+		if !strings.Contains(c.Source, expected) {
+			t.Errorf("missing %q in source:\n%s", expected, c.Source)
+		}
+	}
+	// The document comment belongs above the object
+	if strings.Contains(c.Source, "The example config") {
+		t.Errorf("unexpected document comment in source:\n%s", c.Source)
+	}
+}
@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
@@ -50,15 +45,15 @@ func NewCustomResourceDefinition(obj *apiextensionsv1.CustomResourceDefinition)
 	}
 }
 
-func (k CustomResourceDefinition) Install() (string, []string) {
+func (k CustomResourceDefinition) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}CustomResourceDefinition := %s
+	install := `
+	{{ .GoName }}CustomResourceDefinition := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}CustomResourceDefinition)
 	
 	if client != nil {
@@ -67,20 +62,16 @@ func (k CustomResourceDefinition) Install() (string, []string) {
 			return result.Error()
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("customresourcedefinition.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "apiextensionsv1"), packages
+	return alias(install, "apiextensionsv1"), packages, nil
 }
 
-func (k CustomResourceDefinition) Uninstall() string {
+func (k CustomResourceDefinition) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		result := client.ExtensionsV1beta1().RESTClient().Delete().Namespace(a.Namespace).Name(a.Name).Do(context.TODO())
@@ -90,12 +81,9 @@ func (k CustomResourceDefinition) Uninstall() string {
 	}
  `
 
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("customresourcedefinition.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k CustomResourceDefinition) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"CustomResourceDefinition", "apiextensionsv1")
 }
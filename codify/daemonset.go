@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/kris-nova/logger"
@@ -43,30 +38,30 @@ type DaemonSet struct {
 func NewDaemonSet(obj *appsv1.DaemonSet) *DaemonSet {
 	obj.ObjectMeta = cleanObjectMeta(obj.ObjectMeta)
 	obj.Status = appsv1.DaemonSetStatus{}
+
+	// Ignore resource requirements
+	for i, _ := range obj.Spec.Template.Spec.InitContainers {
+		obj.Spec.Template.Spec.InitContainers[i].Resources = v1.ResourceRequirements{}
+	}
+	for i, _ := range obj.Spec.Template.Spec.Containers {
+		obj.Spec.Template.Spec.Containers[i].Resources = v1.ResourceRequirements{}
+	}
+
 	return &DaemonSet{
 		KubeObject: obj,
 		GoName:     goName(obj.Name),
 	}
 }
 
-func (k DaemonSet) Install() (string, []string) {
-
-	// Ignore resource requirements
-	for i, _ := range k.KubeObject.Spec.Template.Spec.InitContainers {
-		k.KubeObject.Spec.Template.Spec.InitContainers[i].Resources = v1.ResourceRequirements{}
-	}
-	for i, _ := range k.KubeObject.Spec.Template.Spec.Containers {
-		k.KubeObject.Spec.Template.Spec.Containers[i].Resources = v1.ResourceRequirements{}
-	}
-
+func (k DaemonSet) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}DaemonSet := %s
+	install := `
+	{{ .GoName }}DaemonSet := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}DaemonSet)
 
 	if client != nil {
@@ -75,18 +70,15 @@ func (k DaemonSet) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
-	err = tpl.Execute(buf, k)
+`
+	install, err = render("daemonset.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "appsv1"), packages
+	return alias(install, "appsv1"), packages, nil
 }
 
-func (k DaemonSet) Uninstall() string {
+func (k DaemonSet) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.AppsV1().DaemonSets("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -95,13 +87,10 @@ func (k DaemonSet) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("daemonset.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k DaemonSet) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"DaemonSet", "appsv1")
 }
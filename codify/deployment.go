@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/kris-nova/logger"
@@ -43,13 +38,6 @@ type Deployment struct {
 func NewDeployment(obj *appsv1.Deployment) *Deployment {
 	obj.ObjectMeta = cleanObjectMeta(obj.ObjectMeta)
 	obj.Status = appsv1.DeploymentStatus{}
-	return &Deployment{
-		KubeObject: obj,
-		GoName:     goName(obj.Name),
-	}
-}
-
-func (k Deployment) Install() (string, []string) {
 
 	// We do not guarantee the NAML is perfect.
 	//
@@ -58,13 +46,20 @@ func (k Deployment) Install() (string, []string) {
 	// that could possibly be looked up at runtime anyway.
 	//
 	// For now, we ignore the resource requirements.
-	for i, _ := range k.KubeObject.Spec.Template.Spec.InitContainers {
-		k.KubeObject.Spec.Template.Spec.InitContainers[i].Resources = v1.ResourceRequirements{}
+	for i, _ := range obj.Spec.Template.Spec.InitContainers {
+		obj.Spec.Template.Spec.InitContainers[i].Resources = v1.ResourceRequirements{}
+	}
+	for i, _ := range obj.Spec.Template.Spec.Containers {
+		obj.Spec.Template.Spec.Containers[i].Resources = v1.ResourceRequirements{}
 	}
-	for i, _ := range k.KubeObject.Spec.Template.Spec.Containers {
-		k.KubeObject.Spec.Template.Spec.Containers[i].Resources = v1.ResourceRequirements{}
+
+	return &Deployment{
+		KubeObject: obj,
+		GoName:     goName(obj.Name),
 	}
+}
 
+func (k Deployment) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Critical(err.Error())
@@ -72,9 +67,9 @@ func (k Deployment) Install() (string, []string) {
 	l := c.Source
 	packages := c.Packages
 
-	install := fmt.Sprintf(`
+	install := `
 	// Adding a deployment: "{{ .KubeObject.Name }}"
-	{{ .GoName }}Deployment := %s
+	{{ .GoName }}Deployment := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}Deployment)
 
 	if client != nil {
@@ -83,22 +78,15 @@ func (k Deployment) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl, err = tpl.Parse(install)
+`
+	install, err = render("deployment.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Critical(err.Error())
+		return "", nil, err
 	}
-	buf := &bytes.Buffer{}
-	err = tpl.Execute(buf, k)
-	if err != nil {
-		logger.Critical(err.Error())
-	}
-	return alias(buf.String(), "appsv1"), packages
+	return alias(install, "appsv1"), packages, nil
 }
 
-func (k Deployment) Uninstall() string {
+func (k Deployment) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.AppsV1().Deployments("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -107,13 +95,10 @@ func (k Deployment) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("deployment.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k Deployment) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"Deployment", "appsv1")
 }
@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	networkingv1 "k8s.io/api/networking/v1"
 )
@@ -46,15 +41,15 @@ func NewIngressClass(obj *networkingv1.IngressClass) *IngressClass {
 	}
 }
 
-func (k IngressClass) Install() (string, []string) {
+func (k IngressClass) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}IngressClass := %s
+	install := `
+	{{ .GoName }}IngressClass := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}IngressClass)
 
 	if client != nil {
@@ -63,20 +58,16 @@ func (k IngressClass) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("ingressclass.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "networkingv1"), packages
+	return alias(install, "networkingv1"), packages, nil
 }
 
-func (k IngressClass) Uninstall() string {
+func (k IngressClass) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.NetworkingV1().IngressClasses().Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -85,12 +76,9 @@ func (k IngressClass) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("ingressclass.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k IngressClass) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"IngressClass", "networkingv1")
 }
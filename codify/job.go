@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	batchv1 "k8s.io/api/batch/v1"
 )
@@ -47,15 +42,15 @@ func NewJob(obj *batchv1.Job) *Job {
 	}
 }
 
-func (k Job) Install() (string, []string) {
+func (k Job) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}Job := %s
+	install := `
+	{{ .GoName }}Job := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}Job)
 
 	if client != nil {
@@ -64,20 +59,16 @@ func (k Job) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("job.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "batchv1"), packages
+	return alias(install, "batchv1"), packages, nil
 }
 
-func (k Job) Uninstall() string {
+func (k Job) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.BatchV1().Jobs("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -86,12 +77,9 @@ func (k Job) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("job.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k Job) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"Job", "batchv1")
 }
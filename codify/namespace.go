@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/kris-nova/logger"
@@ -47,15 +42,15 @@ func NewNamespace(obj *corev1.Namespace) *Namespace {
 	}
 }
 
-func (k Namespace) Install() (string, []string) {
+func (k Namespace) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}Namespace := %s
+	install := `
+	{{ .GoName }}Namespace := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}Namespace)
 	
 	if client != nil {
@@ -64,20 +59,16 @@ func (k Namespace) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("namespace.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "corev1"), packages
+	return alias(install, "corev1"), packages, nil
 }
 
-func (k Namespace) Uninstall() string {
+func (k Namespace) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.CoreV1().Namespaces().Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -87,12 +78,9 @@ func (k Namespace) Uninstall() string {
 	}
  `
 
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("namespace.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k Namespace) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"Namespace", "corev1")
 }
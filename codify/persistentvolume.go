@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -47,15 +42,15 @@ func NewPersistentVolume(obj *corev1.PersistentVolume) *PersistentVolume {
 	}
 }
 
-func (k PersistentVolume) Install() (string, []string) {
+func (k PersistentVolume) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}PersistentVolume := %s
+	install := `
+	{{ .GoName }}PersistentVolume := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}PersistentVolume)
 
 	if client != nil {
@@ -64,20 +59,16 @@ func (k PersistentVolume) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("persistentvolume.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "corev1"), packages
+	return alias(install, "corev1"), packages, nil
 }
 
-func (k PersistentVolume) Uninstall() string {
+func (k PersistentVolume) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.CoreV1().PersistentVolumes("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -86,12 +77,9 @@ func (k PersistentVolume) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("persistentvolume.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k PersistentVolume) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"PersistentVolume", "corev1")
 }
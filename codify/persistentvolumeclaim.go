@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -41,25 +36,25 @@ type PersistentVolumeClaim struct {
 func NewPersistentVolumeClaim(obj *corev1.PersistentVolumeClaim) *PersistentVolumeClaim {
 	obj.ObjectMeta = cleanObjectMeta(obj.ObjectMeta)
 	obj.Status = corev1.PersistentVolumeClaimStatus{}
+
+	// Ignore resource requirements
+	obj.Spec.Resources = corev1.ResourceRequirements{}
+
 	return &PersistentVolumeClaim{
 		KubeObject: obj,
 		GoName:     goName(obj.Name),
 	}
 }
 
-func (k PersistentVolumeClaim) Install() (string, []string) {
-
-	// Ignore resource requirements
-	k.KubeObject.Spec.Resources = corev1.ResourceRequirements{}
-
+func (k PersistentVolumeClaim) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}PersistentVolumeClaim := %s
+	install := `
+	{{ .GoName }}PersistentVolumeClaim := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}PersistentVolumeClaim)
 
 	if client != nil {
@@ -68,20 +63,16 @@ func (k PersistentVolumeClaim) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("persistentvolumeclaim.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "corev1"), packages
+	return alias(install, "corev1"), packages, nil
 }
 
-func (k PersistentVolumeClaim) Uninstall() string {
+func (k PersistentVolumeClaim) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.CoreV1().PersistentVolumeClaims("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -90,12 +81,9 @@ func (k PersistentVolumeClaim) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("persistentvolumeclaim.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k PersistentVolumeClaim) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"PersistentVolumeClaim", "corev1")
 }
@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -47,15 +42,15 @@ func NewPod(obj *corev1.Pod) *Pod {
 	}
 }
 
-func (k Pod) Install() (string, []string) {
+func (k Pod) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}Pod := %s
+	install := `
+	{{ .GoName }}Pod := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}Pod)
 
 	if client != nil {
@@ -64,19 +59,16 @@ func (k Pod) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("pod.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "corev1"), packages
+	return alias(install, "corev1"), packages, nil
 }
 
-func (k Pod) Uninstall() string {
+func (k Pod) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.CoreV1().Pods("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -85,12 +77,9 @@ func (k Pod) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("pod.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k Pod) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"Pod", "corev1")
 }
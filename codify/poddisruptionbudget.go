@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	policyv1 "k8s.io/api/policy/v1beta1"
 
 	"github.com/kris-nova/logger"
@@ -48,15 +43,15 @@ func NewPodDisruptionBudget(obj *policyv1.PodDisruptionBudget) *PodDisruptionBud
 	}
 }
 
-func (k PodDisruptionBudget) Install() (string, []string) {
+func (k PodDisruptionBudget) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}PodDisruptionBudget := %s
+	install := `
+	{{ .GoName }}PodDisruptionBudget := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}PodDisruptionBudget)
 
 	if client != nil {
@@ -65,19 +60,16 @@ func (k PodDisruptionBudget) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("poddisruptionbudget.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "policyv1"), packages
+	return alias(install, "policyv1"), packages, nil
 }
 
-func (k PodDisruptionBudget) Uninstall() string {
+func (k PodDisruptionBudget) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.PolicyV1beta1().PodDisruptionBudgets("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -86,12 +78,9 @@ func (k PodDisruptionBudget) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("poddisruptionbudget.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k PodDisruptionBudget) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"PodDisruptionBudget", "policyv1")
 }
@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	policyv1 "k8s.io/api/policy/v1beta1"
 
 	"github.com/kris-nova/logger"
@@ -47,15 +42,15 @@ func NewPodSecurityPolicy(obj *policyv1.PodSecurityPolicy) *PodSecurityPolicy {
 	}
 }
 
-func (k PodSecurityPolicy) Install() (string, []string) {
+func (k PodSecurityPolicy) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}PodSecurityPolicy := %s
+	install := `
+	{{ .GoName }}PodSecurityPolicy := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}PodSecurityPolicy)
 
 	if client != nil {
@@ -64,19 +59,16 @@ func (k PodSecurityPolicy) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("podsecuritypolicy.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "policyv1"), packages
+	return alias(install, "policyv1"), packages, nil
 }
 
-func (k PodSecurityPolicy) Uninstall() string {
+func (k PodSecurityPolicy) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.PolicyV1beta1().PodSecurityPolicies().Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -85,12 +77,9 @@ func (k PodSecurityPolicy) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("podsecuritypolicy.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k PodSecurityPolicy) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"PodSecurityPolicy", "policyv1")
 }
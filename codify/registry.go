@@ -0,0 +1,252 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package codify
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/hexops/valast"
+	"github.com/kris-nova/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/yaml"
+)
+
+// Object is a Kubernetes object that can be codified.
+type Object interface {
+
+	// Install returns the snippet of code that would
+	// traditionally live inside a function. This
+	// will define literally (what it can) a struct
+	// for the object, and pass it to the corresponding
+	// kubernetes library.
+	//
+	// An error is returned if the code can not be rendered,
+	// for example from an invalid template.
+	Install() (string, []string, error)
+
+	// Uninstall is the reverse library call of install.
+	Uninstall() (string, error)
+}
+
+// Codifier is used to register support for a kind that is not
+// built into naml, such as a custom resource.
+//
+// Registered codifiers are checked before the built in kinds.
+type Codifier struct {
+
+	// GroupVersionKind is the kind to codify.
+	GroupVersionKind schema.GroupVersionKind
+
+	// AddToScheme will add the Go types for the kind to the
+	// scheme used to decode the YAML. Example: examplev1.AddToScheme
+	AddToScheme func(*runtime.Scheme) error
+
+	// ImportPath is the Go package of the types in the generated code.
+	// Example: github.com/example/operator/api/v1
+	ImportPath string
+
+	// Alias is the import alias for ImportPath in the generated code.
+	// Example: examplev1
+	Alias string
+
+	// Factory will return an Object for a decoded object of the kind.
+	Factory func(obj runtime.Object) (Object, error)
+
+	// ClusterScoped is true when the kind does not have a namespace,
+	// such as a ClusterIssuer.
+	ClusterScoped bool
+}
+
+var (
+	codifiers      = make(map[schema.GroupVersionKind]*Codifier)
+	codifierScheme = runtime.NewScheme()
+	codifierMtx    sync.RWMutex
+)
+
+// RegisterAndExit will register the codifier or exit with an error message
+func RegisterAndExit(c *Codifier) {
+	err := RegisterAndError(c)
+	if err != nil {
+		logger.Critical("%v", err)
+		os.Exit(1)
+	}
+}
+
+// Register a codifier with naml
+func Register(c *Codifier) {
+	RegisterAndExit(c)
+}
+
+// RegisterAndError will register the codifier or return an error
+func RegisterAndError(c *Codifier) error {
+
+	// Validate the codifier
+	if c == nil {
+		return fmt.Errorf("unable to register nil codifier")
+	}
+	if c.GroupVersionKind.Kind == "" || c.GroupVersionKind.Version == "" {
+		return fmt.Errorf("unable to register codifier with empty kind or version: %v", c.GroupVersionKind)
+	}
+	if c.AddToScheme == nil {
+		return fmt.Errorf("unable to register codifier %v: missing AddToScheme", c.GroupVersionKind)
+	}
+	if c.Factory == nil {
+		return fmt.Errorf("unable to register codifier %v: missing Factory", c.GroupVersionKind)
+	}
+	if c.ImportPath == "" || c.Alias == "" {
+		return fmt.Errorf("unable to register codifier %v: missing ImportPath or Alias", c.GroupVersionKind)
+	}
+
+	codifierMtx.Lock()
+	defer codifierMtx.Unlock()
+	err := c.AddToScheme(codifierScheme)
+	if err != nil {
+		return fmt.Errorf("unable to register codifier %v: %v", c.GroupVersionKind, err)
+	}
+	KubernetesImportPackageMap[c.ImportPath] = c.Alias
+	codifiers[c.GroupVersionKind] = c
+	return nil
+}
+
+// Lookup will return the registered codifier for a kind, or nil.
+func Lookup(gvk schema.GroupVersionKind) *Codifier {
+	codifierMtx.RLock()
+	defer codifierMtx.RUnlock()
+	return codifiers[gvk]
+}
+
+// ObjectKinds will return the kinds of an object from the schemes
+// of the registered codifiers.
+func ObjectKinds(obj runtime.Object) ([]schema.GroupVersionKind, error) {
+	codifierMtx.RLock()
+	defer codifierMtx.RUnlock()
+	gvks, _, err := codifierScheme.ObjectKinds(obj)
+	return gvks, err
+}
+
+// Decode will decode raw YAML if the kind has a registered codifier.
+//
+// If the kind is not registered Decode will return a nil *Codifier,
+// and the caller should fall back to the built in kinds.
+func Decode(raw []byte) (runtime.Object, *Codifier, error) {
+	typeMeta := metav1.TypeMeta{}
+	err := yaml.Unmarshal(raw, &typeMeta)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to find kind: %v", err)
+	}
+	c := Lookup(typeMeta.GroupVersionKind())
+	if c == nil {
+		return nil, nil, nil
+	}
+	codifierMtx.RLock()
+	defer codifierMtx.RUnlock()
+	decoded, _, err := serializer.NewCodecFactory(codifierScheme).UniversalDeserializer().Decode(raw, nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode %v: %v", c.GroupVersionKind, err)
+	}
+	return decoded, c, nil
+}
+
+// ImportPackageMap will return a copy of KubernetesImportPackageMap,
+// including the aliases of every registered codifier.
+func ImportPackageMap() map[string]string {
+	codifierMtx.RLock()
+	defer codifierMtx.RUnlock()
+	aliases := make(map[string]string, len(KubernetesImportPackageMap))
+	for path, alias := range KubernetesImportPackageMap {
+		aliases[path] = alias
+	}
+	return aliases
+}
+
+// AliasedLiteral will convert an abstract kubeobject interface{} to Go code
+// like Literal, however every package is written with the alias
+// from KubernetesImportPackageMap.
+//
+// Registered codifiers should use AliasedLiteral, as their types
+// are not known to the naml alias rules.
+func AliasedLiteral(kubeobject interface{}) (*Codified, error) {
+	opt := &valast.Options{
+		PackagePathToName: func(path string) (string, error) {
+			codifierMtx.RLock()
+			defer codifierMtx.RUnlock()
+			if name, ok := KubernetesImportPackageMap[path]; ok {
+				return name, nil
+			}
+			return packagePathToName(path)
+		},
+	}
+	l := valast.StringWithOptions(kubeobject, opt)
+	l = cleanValast20(l)
+	l = commentLiteral(l, kubeobject)
+	r, err := valast.AST(reflect.ValueOf(kubeobject), opt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert to source code: %v", err)
+	}
+	return &Codified{
+		Source:   l,
+		Packages: r.Packages,
+		R:        r,
+		Object:   kubeobject,
+	}, nil
+}
+
+// AliasedConstructor will build a *Constructor for a kubeobject like
+// AliasedLiteral, so registered codifiers can be codified in function mode.
+//
+// Objects returned by a Factory must implement Constructor() to be
+// codified with --functions.
+//
+//	func (k Widget) Constructor() (*codify.Constructor, error) {
+//		return codify.AliasedConstructor(k.KubeObject, k.GoName+"Widget")
+//	}
+func AliasedConstructor(kubeobject interface{}, name string) (*Constructor, error) {
+	c, err := AliasedLiteral(kubeobject)
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf(kubeobject)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	alias, ok := ImportPackageMap()[t.PkgPath()]
+	if !ok {
+		alias, err = packagePathToName(t.PkgPath())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Constructor{
+		Name:     name,
+		Type:     fmt.Sprintf("*%s.%s", alias, t.Name()),
+		Source:   c.Source,
+		Packages: c.Packages,
+	}, nil
+}
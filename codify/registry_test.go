@@ -0,0 +1,128 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package codify
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var testWidgetGVK = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+type testWidget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Size              int `json:"size"`
+}
+
+func (w *testWidget) DeepCopyObject() runtime.Object {
+	c := *w
+	w.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	return &c
+}
+
+type testWidgetObject struct {
+	widget *testWidget
+}
+
+func (k testWidgetObject) Install() (string, []string, error) {
+	c, err := AliasedLiteral(k.widget)
+	if err != nil {
+		return "", nil, err
+	}
+	return c.Source, c.Packages, nil
+}
+
+func (k testWidgetObject) Uninstall() (string, error) {
+	return "", nil
+}
+
+func (k testWidgetObject) Constructor() (*Constructor, error) {
+	return AliasedConstructor(k.widget, "exampleWidget")
+}
+
+func TestRegisterAndDecode(t *testing.T) {
+	err := RegisterAndError(&Codifier{
+		GroupVersionKind: testWidgetGVK,
+		AddToScheme: func(s *runtime.Scheme) error {
+			s.AddKnownTypeWithName(testWidgetGVK, &testWidget{})
+			return nil
+		},
+		ImportPath: "github.com/kris-nova/naml/codify",
+		Alias:      "examplev1",
+		Factory: func(obj runtime.Object) (Object, error) {
+			return testWidgetObject{widget: obj.(*testWidget)}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to register: %v", err)
+	}
+
+	raw := []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: example\nsize: 3\n")
+	decoded, c, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("unable to decode: %v", err)
+	}
+	if c == nil {
+		t.Fatalf("expected registered codifier")
+	}
+	obj, err := c.Factory(decoded)
+	if err != nil {
+		t.Fatalf("unable to create object: %v", err)
+	}
+	install, packages, err := obj.Install()
+	if err != nil {
+		t.Fatalf("unable to install: %v", err)
+	}
+	if !strings.Contains(install, "&examplev1.testWidget{") || !strings.Contains(install, "metav1.ObjectMeta{") {
+		t.Errorf("unexpected install: %s", install)
+	}
+	if len(packages) == 0 {
+		t.Errorf("missing packages")
+	}
+	constructor, err := obj.(testWidgetObject).Constructor()
+	if err != nil {
+		t.Fatalf("unable to build constructor: %v", err)
+	}
+	if constructor.Type != "*examplev1.testWidget" || !strings.HasPrefix(constructor.Source, "&examplev1.testWidget{") {
+		t.Errorf("unexpected constructor: %s %s", constructor.Type, constructor.Source)
+	}
+
+	// Built in kinds are not registered
+	_, c, err = Decode([]byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: example\n"))
+	if err != nil || c != nil {
+		t.Errorf("unexpected codifier for built in kind: %v", err)
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	err := RegisterAndError(&Codifier{GroupVersionKind: testWidgetGVK})
+	if err == nil {
+		t.Errorf("expected error registering codifier without AddToScheme")
+	}
+}
@@ -24,10 +24,7 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
+	"sort"
 
 	"github.com/kris-nova/logger"
 	corev1 "k8s.io/api/core/v1"
@@ -36,6 +33,10 @@ import (
 type Secret struct {
 	KubeObject *corev1.Secret
 	GoName     string
+
+	// LookupKeys are the keys that will be looked up at install time
+	// instead of being defined in the source code.
+	LookupKeys []string
 }
 
 func NewSecret(obj *corev1.Secret) *Secret {
@@ -46,15 +47,53 @@ func NewSecret(obj *corev1.Secret) *Secret {
 	}
 }
 
-func (k Secret) Install() (string, []string) {
+// EnableLookup will remove every non empty value from the Secret Data and StringData
+// and will instead look up the values at install time with naml.LookupSecretData().
+//
+// This keeps secret values out of the generated source code.
+func (k *Secret) EnableLookup() {
+	keys := make(map[string]bool)
+	for key, value := range k.KubeObject.Data {
+		if len(value) == 0 {
+			continue
+		}
+		keys[key] = true
+		delete(k.KubeObject.Data, key)
+	}
+	for key, value := range k.KubeObject.StringData {
+		if value == "" {
+			continue
+		}
+		keys[key] = true
+		delete(k.KubeObject.StringData, key)
+	}
+	if len(k.KubeObject.Data) == 0 {
+		k.KubeObject.Data = nil
+	}
+	if len(k.KubeObject.StringData) == 0 {
+		k.KubeObject.StringData = nil
+	}
+	for key := range keys {
+		k.LookupKeys = append(k.LookupKeys, key)
+	}
+	sort.Strings(k.LookupKeys)
+}
+
+func (k Secret) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}Secret := %s
+	install := `
+	{{ .GoName }}Secret := {{ .Literal }}
+	{{- if .LookupKeys }}
+	err = naml.LookupSecretData({{ .GoName }}Secret{{ range .LookupKeys }}, {{ printf "%q" . }}{{ end }})
+	if err != nil {
+		return err
+	}
+	{{- end }}
 	x.objects = append(x.objects, {{ .GoName }}Secret)
 
 	if client != nil {
@@ -63,20 +102,16 @@ func (k Secret) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("secret.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l, LookupKeys: k.LookupKeys})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "corev1"), packages
+	return alias(install, "corev1"), packages, nil
 }
 
-func (k Secret) Uninstall() string {
+func (k Secret) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.CoreV1().Secrets("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -85,12 +120,9 @@ func (k Secret) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("secret.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, LookupKeys: k.LookupKeys})
+}
+
+func (k Secret) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"Secret", "corev1")
 }
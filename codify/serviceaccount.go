@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	"github.com/kris-nova/logger"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -46,15 +41,15 @@ func NewServiceAccount(obj *corev1.ServiceAccount) *ServiceAccount {
 	}
 }
 
-func (k ServiceAccount) Install() (string, []string) {
+func (k ServiceAccount) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}ServiceAccount := %s
+	install := `
+	{{ .GoName }}ServiceAccount := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}ServiceAccount)
 	
 	if client != nil {
@@ -63,20 +58,16 @@ func (k ServiceAccount) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("serviceaccount.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "corev1"), packages
+	return alias(install, "corev1"), packages, nil
 }
 
-func (k ServiceAccount) Uninstall() string {
+func (k ServiceAccount) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.CoreV1().ServiceAccounts("{{ .KubeObject.Namespace }}").Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -85,12 +76,9 @@ func (k ServiceAccount) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("serviceaccount.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k ServiceAccount) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"ServiceAccount", "corev1")
 }
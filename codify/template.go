@@ -0,0 +1,227 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package codify
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1beta1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	// TemplateExtension is the file extension for every template in a template directory.
+	TemplateExtension = ".tpl"
+
+	// MainTemplate is the name of the template for a main package.
+	MainTemplate = "main.go"
+
+	// LibraryTemplate is the name of the template for a library package.
+	LibraryTemplate = "library.go"
+)
+
+// TemplateKinds are the kinds with built in templates. Every kind has
+// an install and an uninstall template, for example deployment.install.tpl
+//
+// The value is the type of the KubeObject for the kind.
+var TemplateKinds = map[string]interface{}{
+	"clusterrole":                    &rbacv1.ClusterRole{},
+	"clusterrolebinding":             &rbacv1.ClusterRoleBinding{},
+	"configmap":                      &corev1.ConfigMap{},
+	"cronjob":                        &batchv1.CronJob{},
+	"customresourcedefinition":       &apiextensionsv1.CustomResourceDefinition{},
+	"daemonset":                      &appsv1.DaemonSet{},
+	"deployment":                     &appsv1.Deployment{},
+	"ingress":                        &networkingv1.Ingress{},
+	"ingressclass":                   &networkingv1.IngressClass{},
+	"job":                            &batchv1.Job{},
+	"namespace":                      &corev1.Namespace{},
+	"persistentvolume":               &corev1.PersistentVolume{},
+	"persistentvolumeclaim":          &corev1.PersistentVolumeClaim{},
+	"pod":                            &corev1.Pod{},
+	"poddisruptionbudget":            &policyv1.PodDisruptionBudget{},
+	"podsecuritypolicy":              &policyv1.PodSecurityPolicy{},
+	"role":                           &rbacv1.Role{},
+	"rolebinding":                    &rbacv1.RoleBinding{},
+	"secret":                         &corev1.Secret{},
+	"service":                        &corev1.Service{},
+	"serviceaccount":                 &corev1.ServiceAccount{},
+	"statefulset":                    &appsv1.StatefulSet{},
+	"validatingwebhookconfiguration": &admissionregistrationv1.ValidatingWebhookConfiguration{},
+}
+
+// TemplateValues are the values available in every kind template.
+type TemplateValues struct {
+
+	// KubeObject is the Kubernetes object
+	KubeObject interface{} `description:"The Kubernetes object. Fields are accessed by their Go name, for example .KubeObject.Namespace"`
+
+	// GoName is the name of the object that is safe to use in Go
+	GoName string `description:"The name of the object that is safe to use in a Go identifier."`
+
+	// Literal is the Go code that defines the object
+	Literal string `description:"The Go code that defines the object. Only set in install templates."`
+
+	// LookupKeys are the Secret keys that will be looked up at install time
+	LookupKeys []string `description:"The keys that are looked up at install time with naml.LookupSecretData(). Only set for Secrets with --secret-lookup."`
+}
+
+// Templates are the templates loaded from a template directory with
+// LoadTemplates. They are used instead of the built in templates with
+// the same name.
+//
+// A nil Templates will use every built in template.
+type Templates struct {
+	overrides map[string]*template.Template
+}
+
+var (
+	objectTemplates    = make(map[interface{}]*Templates)
+	objectTemplatesMtx sync.Mutex
+)
+
+// TemplateNames will return the name of every template that can be overridden.
+func TemplateNames() []string {
+	names := []string{MainTemplate, LibraryTemplate}
+	for kind := range TemplateKinds {
+		names = append(names, kind+".install", kind+".uninstall")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTemplates will load every template in the directory, which
+// can be used instead of the built in templates with the same name.
+//
+// Templates are named after the built in template with the
+// TemplateExtension. Example: deployment.install.tpl
+//
+// An empty directory will return Templates without any overrides.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{
+		overrides: make(map[string]*template.Template),
+	}
+	if dir == "" {
+		return t, nil
+	}
+	known := make(map[string]bool)
+	for _, name := range TemplateNames() {
+		known[name] = true
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template directory: %v", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != TemplateExtension {
+			continue
+		}
+		name := strings.TrimSuffix(file.Name(), TemplateExtension)
+		if !known[name] {
+			return nil, fmt.Errorf("unknown template %s, expected one of: %s", file.Name(), strings.Join(TemplateNames(), ", "))
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read template %s: %v", file.Name(), err)
+		}
+		tpl, err := template.New(name).Parse(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse template %s: %v", file.Name(), err)
+		}
+		t.overrides[name] = tpl
+	}
+	return t, nil
+}
+
+// Names will return the sorted names of every template that is
+// loaded from the template directory.
+func (t *Templates) Names() []string {
+	var names []string
+	if t == nil {
+		return names
+	}
+	for name := range t.overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Template will return the template with the name, which is the
+// template loaded from the template directory if there is one.
+func (t *Templates) Template(name, text string) (*template.Template, error) {
+	if t != nil {
+		if tpl, ok := t.overrides[name]; ok {
+			return tpl, nil
+		}
+	}
+	return template.New(name).Parse(text)
+}
+
+// SetTemplates will set the templates that are used to render the
+// install and uninstall code for a kubeobject.
+//
+// The kubeobject must be the same pointer that is passed to the
+// templates as the KubeObject. Setting nil will remove the templates,
+// which naml.Codify does for every object it decodes before it returns.
+func SetTemplates(kubeobject interface{}, t *Templates) {
+	objectTemplatesMtx.Lock()
+	defer objectTemplatesMtx.Unlock()
+	if t == nil {
+		delete(objectTemplates, kubeobject)
+		return
+	}
+	objectTemplates[kubeobject] = t
+}
+
+// render will render a kind template with the values, using the
+// templates that are set for the KubeObject.
+func render(name, text string, values TemplateValues) (string, error) {
+	objectTemplatesMtx.Lock()
+	templates := objectTemplates[values.KubeObject]
+	objectTemplatesMtx.Unlock()
+	tpl, err := templates.Template(name, text)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse template %s: %v", name, err)
+	}
+	buf := &bytes.Buffer{}
+	err = tpl.Execute(buf, values)
+	if err != nil {
+		return "", fmt.Errorf("unable to render template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
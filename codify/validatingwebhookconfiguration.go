@@ -24,11 +24,6 @@
 package codify
 
 import (
-	"bytes"
-	"fmt"
-	"text/template"
-	"time"
-
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 
 	"github.com/kris-nova/logger"
@@ -47,15 +42,15 @@ func NewValidatingwebhookConfiguration(obj *admissionregistrationv1.ValidatingWe
 	}
 }
 
-func (k ValidatingwebhookConfiguration) Install() (string, []string) {
+func (k ValidatingwebhookConfiguration) Install() (string, []string, error) {
 	c, err := Literal(k.KubeObject)
 	if err != nil {
 		logger.Debug(err.Error())
 	}
 	l := c.Source
 	packages := c.Packages
-	install := fmt.Sprintf(`
-	{{ .GoName }}ValidatingwebhookConfiguration := %s
+	install := `
+	{{ .GoName }}ValidatingwebhookConfiguration := {{ .Literal }}
 	x.objects = append(x.objects, {{ .GoName }}ValidatingwebhookConfiguration)
 
 	if client != nil {
@@ -64,20 +59,16 @@ func (k ValidatingwebhookConfiguration) Install() (string, []string) {
 			return err
 		}
 	}
-`, l)
-
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(install)
-	buf := &bytes.Buffer{}
+`
 	k.KubeObject.Name = sanitizeK8sObjectName(k.KubeObject.Name)
-	err = tpl.Execute(buf, k)
+	install, err = render("validatingwebhookconfiguration.install", install, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName, Literal: l})
 	if err != nil {
-		logger.Debug(err.Error())
+		return "", nil, err
 	}
-	return alias(buf.String(), "admissionregistrationv1"), packages
+	return alias(install, "admissionregistrationv1"), packages, nil
 }
 
-func (k ValidatingwebhookConfiguration) Uninstall() string {
+func (k ValidatingwebhookConfiguration) Uninstall() (string, error) {
 	uninstall := `
 	if client != nil {
 		err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(context.TODO(), "{{ .KubeObject.Name }}", metav1.DeleteOptions{})
@@ -86,12 +77,9 @@ func (k ValidatingwebhookConfiguration) Uninstall() string {
 		}
 	}
  `
-	tpl := template.New(fmt.Sprintf("%s", time.Now().String()))
-	tpl.Parse(uninstall)
-	buf := &bytes.Buffer{}
-	err := tpl.Execute(buf, k)
-	if err != nil {
-		logger.Debug(err.Error())
-	}
-	return buf.String()
+	return render("validatingwebhookconfiguration.uninstall", uninstall, TemplateValues{KubeObject: k.KubeObject, GoName: k.GoName})
+}
+
+func (k ValidatingwebhookConfiguration) Constructor() (*Constructor, error) {
+	return newConstructor(k.KubeObject, k.GoName+"ValidatingwebhookConfiguration", "admissionregistrationv1")
 }
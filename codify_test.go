@@ -25,7 +25,15 @@ package naml
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/kris-nova/naml/codify"
 )
 
 func TestYAMLDelimiterBottom(t *testing.T) {
@@ -309,3 +317,142 @@ spec:
 		t.Errorf("inline YAML delimiter split: %d", len(objects))
 	}
 }
+
+func TestYAMLDocumentComments(t *testing.T) {
+
+	testString := `# Comment only document
+---
+# The example service
+apiVersion: v1
+kind: Service
+metadata:
+  name: example
+`
+
+	objects, comments, delta, err := codifyDocuments([]byte(testString), nil)
+	if err != nil {
+		t.Errorf("YAML comments check: %v", err)
+	}
+	if delta != 0 {
+		t.Errorf("Failure parsing YAML systems: %d", delta)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("YAML comments split: %d", len(objects))
+	}
+	expected := []string{"Comment only document", "The example service"}
+	if !reflect.DeepEqual(comments[objects[0]], expected) {
+		t.Errorf("unexpected document comments: %v", comments[objects[0]])
+	}
+}
+
+func TestCodifyRunComments(t *testing.T) {
+
+	testString := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: example
+data:
+  # The example key
+  key: value
+`
+
+	run := &codifyRun{}
+	_, _, _, err := codifyDocuments([]byte(testString), run)
+	if err != nil {
+		t.Fatalf("YAML comments check: %v", err)
+	}
+	if len(run.kubeobjects) != 1 {
+		t.Fatalf("expected 1 decoded object, got %d", len(run.kubeobjects))
+	}
+	kubeobject := run.kubeobjects[0]
+	if codify.GetComments(kubeobject) == nil {
+		t.Errorf("expected comments to be set during the run")
+	}
+	run.done()
+	if codify.GetComments(kubeobject) != nil {
+		t.Errorf("expected comments to be removed when the run is done")
+	}
+}
+
+// constructorObject is a CodifyObject with a fixed constructor name
+type constructorObject struct {
+	name string
+}
+
+func (c *constructorObject) Install() (string, []string, error) { return "", nil, nil }
+func (c *constructorObject) Uninstall() (string, error)         { return "", nil }
+func (c *constructorObject) Constructor() (*codify.Constructor, error) {
+	return &codify.Constructor{Name: c.name, Type: "*v1.ConfigMap", Source: "nil"}, nil
+}
+
+func TestCodifyFunctionNames(t *testing.T) {
+	objs := []CodifyObject{
+		&constructorObject{name: "webConfigMap"},
+		&constructorObject{name: "webConfigMap"},
+		&constructorObject{name: "webConfigMap2"},
+		&constructorObject{name: "webConfigMap"},
+	}
+	v := &CodifyValues{AppNameTitle: "App"}
+	err := codifyFunctions(objs, map[CodifyObject][]string{}, v, map[string]bool{})
+	if err != nil {
+		t.Fatalf("unable to codify functions: %v", err)
+	}
+	expected := "x.webConfigMap(),\nx.webConfigMap3(),\nx.webConfigMap2(),\nx.webConfigMap4(),\n"
+	if v.Constructors != expected {
+		t.Errorf("expected unique constructor names %q, got %q", expected, v.Constructors)
+	}
+	if n := strings.Count(v.Functions, "func (x *App) webConfigMap2()"); n != 1 {
+		t.Errorf("expected webConfigMap2 to be defined once, got %d", n)
+	}
+}
+
+func TestGitAuthor(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	// Only the global config in a temporary home is used, which works
+	// with every version of git. GIT_CONFIG_GLOBAL is set for git 2.32
+	// and newer, which would otherwise use the global config of the user.
+	home := t.TempDir()
+	config := filepath.Join(home, ".gitconfig")
+	err := ioutil.WriteFile(config, []byte("[user]\n\tname = Jane Doe\n\temail = jane@example.com\n"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write git config: %v", err)
+	}
+	env := map[string]string{
+		"HOME":                home,
+		"XDG_CONFIG_HOME":     home,
+		"GIT_CONFIG_GLOBAL":   config,
+		"GIT_CONFIG_NOSYSTEM": "1",
+		"GIT_CONFIG_COUNT":    "0",
+	}
+	for key, value := range env {
+		original, ok := os.LookupEnv(key)
+		os.Setenv(key, value)
+		if ok {
+			defer os.Setenv(key, original)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+
+	// Run outside of any repository so its local config is not used
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	name, email := gitAuthor()
+	if name != "Jane Doe" || email != "<jane@example.com>" {
+		t.Errorf("unexpected author: %s %s", name, email)
+	}
+	if value := gitConfig("naml.unknown"); value != "" {
+		t.Errorf("expected empty value for unknown key, got %s", value)
+	}
+}
@@ -0,0 +1,284 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigFile is the name of the naml config file that is read from
+// the home directory and then the working directory.
+const ConfigFile = ".naml.yaml"
+
+// Config is a .naml.yaml file with default values for the command line.
+//
+//	flags:
+//	  kubeconfig: ~/.kube/work
+//	commands:
+//	  output:
+//	    output: json
+//	codify:
+//	  authorName: Jane Doe
+//	  authorEmail: <jane@example.com>
+//
+// Flags passed on the command line always win over the config.
+type Config struct {
+
+	// Flags are default values for the global flags, and for every
+	// flag of a command with the same name.
+	Flags map[string]interface{} `json:"flags,omitempty"`
+
+	// Commands are default values for the flags of a single command.
+	Commands map[string]map[string]interface{} `json:"commands,omitempty"`
+
+	// Codify are default CodifyValues, by field name.
+	Codify map[string]interface{} `json:"codify,omitempty"`
+}
+
+// codifyConfigFlags are the codify flags that set each CodifyValues field
+var codifyConfigFlags = map[string]string{
+	"LibraryMode":  "library",
+	"FunctionMode": "functions",
+	"SecretLookup": "secret-lookup",
+	"Hoist":        "hoist",
+	"TemplateDir":  "template-dir",
+	"AuthorName":   "author-name",
+	"AuthorEmail":  "author-email",
+	"Description":  "description",
+	"PackageName":  "package-name",
+	"AppNameTitle": "name",
+	"AppNameLower": "name",
+}
+
+// ConfigPaths will return the paths of the config files in order,
+// the home directory first so that the working directory wins.
+func ConfigPaths() []string {
+	var paths []string
+	home, err := os.UserHomeDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(home, ConfigFile))
+	}
+	wd, err := os.Getwd()
+	if err == nil && wd != home {
+		paths = append(paths, filepath.Join(wd, ConfigFile))
+	}
+	return paths
+}
+
+// LoadConfig will read and merge every config file that exists in paths.
+// Later files win over earlier files.
+func LoadConfig(paths ...string) (*Config, error) {
+	merged := make(map[string]interface{})
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read config: %v", err)
+		}
+		raw, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+		var values map[string]interface{}
+		err = json.Unmarshal(raw, &values)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+		mergeConfig(merged, values)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	err = DecodeValues(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	return config, nil
+}
+
+// mergeConfig will merge src into dst, merging nested maps.
+func mergeConfig(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcOk := value.(map[string]interface{})
+		dstMap, dstOk := dst[key].(map[string]interface{})
+		if srcOk && dstOk {
+			mergeConfig(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// Apply will set the default values of the flags of a command line
+// application, and the default CodifyValues.
+//
+// Unknown flags, commands and CodifyValues fields are an error.
+func (c *Config) Apply(app *cli.App, values *CodifyValues) error {
+	commands := make(map[string]map[string]interface{})
+	for name, flags := range c.Commands {
+		commands[name] = make(map[string]interface{})
+		for flag, value := range flags {
+			commands[name][flag] = value
+		}
+	}
+
+	// Codify values that are set with a flag are set as the
+	// default of the flag, as the flag would overwrite them
+	if len(c.Codify) > 0 {
+		data, err := json.Marshal(c.Codify)
+		if err != nil {
+			return err
+		}
+		err = DecodeValues(data, values)
+		if err != nil {
+			return fmt.Errorf("invalid codify config: %v", err)
+		}
+		if commands["codify"] == nil {
+			commands["codify"] = make(map[string]interface{})
+		}
+		v := reflect.ValueOf(values).Elem()
+		for key := range c.Codify {
+			for field, flag := range codifyConfigFlags {
+				if !strings.EqualFold(key, field) {
+					continue
+				}
+				if _, ok := commands["codify"][flag]; !ok {
+					commands["codify"][flag] = v.FieldByName(field).Interface()
+				}
+			}
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, flag := range app.Flags {
+		name := flagName(flag)
+		value, ok := c.Flags[name]
+		if !ok {
+			continue
+		}
+		used[name] = true
+		err := setFlagDefault(flag, value)
+		if err != nil {
+			return err
+		}
+	}
+	for _, command := range app.Commands {
+		for _, flag := range command.Flags {
+			name := flagName(flag)
+			value, ok := c.Flags[name]
+			if ok {
+				used[name] = true
+			}
+			if v, found := commands[command.Name][name]; found {
+				value, ok = v, true
+				delete(commands[command.Name], name)
+			}
+			if !ok {
+				continue
+			}
+			err := setFlagDefault(flag, value)
+			if err != nil {
+				return fmt.Errorf("%s: %v", command.Name, err)
+			}
+		}
+		for name := range commands[command.Name] {
+			return fmt.Errorf("unknown flag %q for command %s in config", name, command.Name)
+		}
+		delete(commands, command.Name)
+	}
+	for name := range commands {
+		return fmt.Errorf("unknown command %q in config", name)
+	}
+	var unknown []string
+	for name := range c.Flags {
+		if !used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown flags in config: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// flagName will return the long name of a flag.
+func flagName(flag cli.Flag) string {
+	return flag.Names()[0]
+}
+
+// setFlagDefault will set the default value of a flag from a config value.
+func setFlagDefault(flag cli.Flag, value interface{}) error {
+	switch f := flag.(type) {
+	case *cli.StringFlag:
+		switch v := value.(type) {
+		case string:
+			f.Value = v
+		case float64, bool:
+			f.Value = fmt.Sprintf("%v", v)
+		default:
+			return fmt.Errorf("invalid value for flag %s, expected a string: %v", f.Name, value)
+		}
+	case *cli.BoolFlag:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("invalid value for flag %s, expected true or false: %v", f.Name, value)
+		}
+		f.Value = b
+	case *cli.StringSliceFlag:
+		var items []string
+		switch v := value.(type) {
+		case string:
+			items = append(items, v)
+		case []interface{}:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("invalid value for flag %s, expected a list of strings: %v", f.Name, value)
+				}
+				items = append(items, s)
+			}
+		default:
+			return fmt.Errorf("invalid value for flag %s, expected a list of strings: %v", f.Name, value)
+		}
+		f.Value = cli.NewStringSlice(items...)
+	default:
+		return fmt.Errorf("unsupported flag %s", flagName(flag))
+	}
+	return nil
+}
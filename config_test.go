@@ -0,0 +1,170 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	err := ioutil.WriteFile(path, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func configApp(kubeconfig, output *string, with *cli.StringSlice, values *CodifyValues) *cli.App {
+	return &cli.App{
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "kubeconfig", Value: "~/.kube/config", Destination: kubeconfig},
+			&cli.StringSliceFlag{Name: "with", Destination: with},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:   "codify",
+				Action: func(c *cli.Context) error { return nil },
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "author-name", Value: "Kris Nóva", Destination: &values.AuthorName},
+				},
+			},
+			{
+				Name:   "output",
+				Action: func(c *cli.Context) error { return nil },
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: "yaml", Destination: output},
+				},
+			},
+		},
+	}
+}
+
+func TestConfig(t *testing.T) {
+	home := writeConfig(t, `
+flags:
+  kubeconfig: ~/.kube/home
+  with: [a.naml, b.naml]
+codify:
+  authorName: Home Author
+  version: 1.0.0
+`)
+	wd := writeConfig(t, `
+flags:
+  kubeconfig: ~/.kube/work
+commands:
+  output:
+    output: json
+`)
+	config, err := LoadConfig(home, wd, filepath.Join(t.TempDir(), ConfigFile))
+	if err != nil {
+		t.Fatalf("unable to load config: %v", err)
+	}
+
+	var kubeconfig, output string
+	var with cli.StringSlice
+	values := &CodifyValues{AuthorName: "Kris Nóva", Version: "0.0.1"}
+	app := configApp(&kubeconfig, &output, &with, values)
+	err = config.Apply(app, values)
+	if err != nil {
+		t.Fatalf("unable to apply config: %v", err)
+	}
+	if values.Version != "1.0.0" {
+		t.Errorf("expected codify version from config, got %s", values.Version)
+	}
+
+	err = app.Run([]string{"naml", "codify"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeconfig != "~/.kube/work" {
+		t.Errorf("expected the working directory to win, got %s", kubeconfig)
+	}
+	if len(with.Value()) != 2 {
+		t.Errorf("expected with from config, got %v", with.Value())
+	}
+	if values.AuthorName != "Home Author" {
+		t.Errorf("expected author from config, got %s", values.AuthorName)
+	}
+
+	// Command line flags win
+	err = app.Run([]string{"naml", "--kubeconfig", "/tmp/kubeconfig", "--with", "c.naml", "output", "-o", "jsonl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kubeconfig != "/tmp/kubeconfig" || output != "jsonl" {
+		t.Errorf("expected flags to win, got %s %s", kubeconfig, output)
+	}
+	if v := with.Value(); len(v) != 1 || v[0] != "c.naml" {
+		t.Errorf("expected flags to replace the config list, got %v", v)
+	}
+}
+
+func TestConfigOutput(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, "commands:\n  output:\n    output: json\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kubeconfig, output string
+	var with cli.StringSlice
+	values := &CodifyValues{}
+	app := configApp(&kubeconfig, &output, &with, values)
+	err = config.Apply(app, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.Run([]string{"naml", "output"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "json" {
+		t.Errorf("expected output from config, got %s", output)
+	}
+}
+
+func TestConfigUnknown(t *testing.T) {
+	for _, data := range []string{
+		"kubeconfig: ~/.kube/config\n",
+		"flags:\n  namespace: web\n",
+		"commands:\n  install:\n    output: json\n",
+		"commands:\n  codify:\n    output: json\n",
+		"codify:\n  authorNme: Jane\n",
+		"flags:\n  kubeconfig: [a, b]\n",
+	} {
+		config, err := LoadConfig(writeConfig(t, data))
+		if err == nil {
+			var kubeconfig, output string
+			var with cli.StringSlice
+			values := &CodifyValues{}
+			err = config.Apply(configApp(&kubeconfig, &output, &with, values), values)
+		}
+		if err == nil {
+			t.Errorf("expected error for config %q", data)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kris-nova/logger"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KustomizationFile is the name of the kustomization written
+	// alongside the manifest files.
+	KustomizationFile string = "kustomization.yaml"
+)
+
+// Kustomization is the minimal kustomization.yaml that lists
+// every manifest file as a resource.
+type Kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// RunOutputDir will write every object of an application to
+// its own file in dir.
+func RunOutputDir(appName string, o OutputEncoding, dir string) error {
+	app := Find(appName)
+	if app == nil {
+		return fmt.Errorf("unable to find app: %s", appName)
+	}
+
+	err := Configure(app)
+	if err != nil {
+		return err
+	}
+
+	// Install the application "nowhere" to register the components in memory
+	err = app.Install(nil)
+	if err != nil {
+		return fmt.Errorf("unable to install app in memory: %v", err)
+	}
+	var files []string
+	switch o {
+	case OutputHelm:
+		files, err = WriteHelmChart(app, dir)
+	default:
+		files, err = WriteDir(app, o, dir)
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		logger.Info("Wrote %s", filepath.Join(dir, file))
+	}
+	return nil
+}
+
+// WriteDir will write every object of an application to its own file
+// named <kind>-<namespace>-<name>.yaml in install order, and
+// a kustomization.yaml that lists the files.
+//
+// WriteDir returns the names of the files that were written.
+func WriteDir(app Deployable, o OutputEncoding, dir string) ([]string, error) {
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create directory %s: %v", dir, err)
+	}
+	var resources []string
+	seen := make(map[string]bool)
+	for _, obj := range objs {
+		var raw []byte
+		switch o {
+		case OutputJSON, OutputJSONLines:
+			raw, err = KubeJSON(obj)
+		default:
+			raw, err = KubeYAML(obj)
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, err := ObjectFileName(obj, o)
+		if err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("unable to write %s: more than one object with the same file name", name)
+		}
+		seen[name] = true
+		err = ioutil.WriteFile(filepath.Join(dir, name), raw, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write %s: %v", name, err)
+		}
+		resources = append(resources, name)
+	}
+	raw, err := yaml.Marshal(&Kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal %s: %v", KustomizationFile, err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, KustomizationFile), raw, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write %s: %v", KustomizationFile, err)
+	}
+	return append(resources, KustomizationFile), nil
+}
+
+// fileNameUnsafe matches everything that should not be in a file name
+var fileNameUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// ObjectFileName will return the file name for an object
+// as <kind>-<namespace>-<name>.yaml
+//
+// The namespace is left out for objects without a namespace.
+func ObjectFileName(obj runtime.Object, o OutputEncoding) (string, error) {
+	gvk, err := ObjectKind(obj)
+	if err != nil {
+		return "", err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("unable to find metadata for %T: %v", obj, err)
+	}
+	parts := []string{gvk.Kind}
+	if accessor.GetNamespace() != "" {
+		parts = append(parts, accessor.GetNamespace())
+	}
+	parts = append(parts, accessor.GetName())
+	name := fileNameUnsafe.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-")
+	switch o {
+	case OutputJSON, OutputJSONLines:
+		return name + ".json", nil
+	default:
+		return name + ".yaml", nil
+	}
+}
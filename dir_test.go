@@ -0,0 +1,103 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWriteDir(t *testing.T) {
+	deployment := BusyboxDeployment("example")
+	deployment.Namespace = "default"
+	app := &testApp{
+		objects: []runtime.Object{
+			&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "system:example"}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "default"}},
+			deployment,
+		},
+	}
+	dir := t.TempDir()
+	files, err := WriteDir(app, OutputYAML, dir)
+	if err != nil {
+		t.Fatalf("unable to write dir: %v", err)
+	}
+	expected := []string{
+		"clusterrole-system-example.yaml",
+		"service-default-example.yaml",
+		"deployment-default-example.yaml",
+		KustomizationFile,
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected files %v, got %v", expected, files)
+	}
+
+	// Every manifest can be decoded by Kubernetes
+	for _, file := range files[:len(files)-1] {
+		raw, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", file, err)
+		}
+		_, _, err = scheme.Codecs.UniversalDeserializer().Decode(raw, nil, nil)
+		if err != nil {
+			t.Errorf("unable to decode %s: %v", file, err)
+		}
+	}
+
+	// The kustomization lists the manifests in install order
+	raw, err := ioutil.ReadFile(filepath.Join(dir, KustomizationFile))
+	if err != nil {
+		t.Fatalf("unable to read %s: %v", KustomizationFile, err)
+	}
+	kustomization := &Kustomization{}
+	err = yaml.Unmarshal(raw, kustomization)
+	if err != nil {
+		t.Fatalf("unable to unmarshal %s: %v", KustomizationFile, err)
+	}
+	if !reflect.DeepEqual(kustomization.Resources, expected[:len(expected)-1]) {
+		t.Errorf("expected resources %v, got %v", expected[:len(expected)-1], kustomization.Resources)
+	}
+}
+
+func TestWriteDirDuplicate(t *testing.T) {
+	app := &testApp{
+		objects: []runtime.Object{
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+		},
+	}
+	_, err := WriteDir(app, OutputYAML, t.TempDir())
+	if err == nil {
+		t.Errorf("expected error for duplicate file names")
+	}
+}
@@ -3,12 +3,14 @@ module github.com/kris-nova/naml
 go 1.16
 
 require (
+	github.com/evanphx/json-patch v4.11.0+incompatible
 	github.com/fatih/color v1.12.0
 	github.com/hexops/valast v1.4.1
 	github.com/kris-nova/logger v0.2.2
 	github.com/urfave/cli/v2 v2.3.0
 	google.golang.org/grpc v1.38.0 // indirect
 	google.golang.org/protobuf v1.26.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	k8s.io/api v0.22.0
 	k8s.io/apiextensions-apiserver v0.22.0
 	k8s.io/apimachinery v0.22.0
@@ -0,0 +1,296 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// HelmChartFile is the name of the chart definition.
+	HelmChartFile string = "Chart.yaml"
+
+	// HelmValuesFile is the name of the default values of the chart.
+	HelmValuesFile string = "values.yaml"
+
+	// HelmTemplatesDir is the directory of the chart templates.
+	HelmTemplatesDir string = "templates"
+
+	// HelmDefaultVersion is the chart version used when the
+	// app does not have a ResourceVersion.
+	HelmDefaultVersion string = "0.1.0"
+)
+
+// HelmChart is the Chart.yaml of a Helm chart.
+type HelmChart struct {
+	APIVersion  string `json:"apiVersion"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	Version     string `json:"version"`
+	AppVersion  string `json:"appVersion"`
+}
+
+// HelmValues is the values.yaml of a Helm chart.
+//
+// Workloads are keyed by lower case kind and then by name, so workloads
+// of different kinds can have the same name. Their images are keyed by
+// container name.
+//
+//	workloads:
+//	  deployment:
+//	    web:
+//	      replicas: 1
+//	  cronjob:
+//	    web:
+//	      images:
+//	        web: busybox
+type HelmValues struct {
+	Namespace string                              `json:"namespace,omitempty"`
+	Workloads map[string]map[string]*HelmWorkload `json:"workloads,omitempty"`
+}
+
+// HelmWorkload is the values of a single workload.
+type HelmWorkload struct {
+	Replicas *int64            `json:"replicas,omitempty"`
+	Images   map[string]string `json:"images,omitempty"`
+}
+
+// helmPodSpecPaths are the paths to the pod spec of each workload kind.
+var helmPodSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// helmVersion matches a SemVer 2 version, which Helm requires for the
+// version of a chart. See https://semver.org
+var helmVersion = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// helmDelimiters matches template delimiters that are already in the
+// objects, which must be escaped so Helm does not render them.
+var helmDelimiters = regexp.MustCompile(`{{|}}`)
+
+// WriteHelmChart will write an application as a Helm chart in dir.
+//
+// Chart.yaml is generated from the AppMeta, every object is written to
+// its own file in templates/, and values.yaml exposes the namespace and
+// the replicas and images of every workload.
+//
+// WriteHelmChart returns the names of the files that were written.
+func WriteHelmChart(app Deployable, dir string) ([]string, error) {
+	meta := app.Meta()
+	version := meta.ResourceVersion
+	if version == "" {
+		version = HelmDefaultVersion
+	}
+	if !helmVersion.MatchString(version) {
+		return nil, fmt.Errorf("invalid chart version %q: the ResourceVersion of the app must be a SemVer 2 version such as %s", version, HelmDefaultVersion)
+	}
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Join(dir, HelmTemplatesDir), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create directory %s: %v", dir, err)
+	}
+
+	// [ Chart.yaml ]
+	files := map[string]interface{}{
+		HelmChartFile: &HelmChart{
+			APIVersion:  "v2",
+			Name:        meta.Name,
+			Description: meta.Description,
+			Type:        "application",
+			Version:     version,
+			AppVersion:  version,
+		},
+	}
+	names := []string{HelmChartFile, HelmValuesFile}
+
+	// [ templates/ ]
+	values := &HelmValues{
+		Namespace: helmNamespace(objs),
+		Workloads: make(map[string]map[string]*HelmWorkload),
+	}
+	templates := make(map[string][]byte)
+	for _, obj := range objs {
+		u, err := outputUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		name, err := ObjectFileName(obj, OutputYAML)
+		if err != nil {
+			return nil, err
+		}
+		name = filepath.Join(HelmTemplatesDir, name)
+		if _, ok := templates[name]; ok {
+			return nil, fmt.Errorf("unable to write %s: more than one object with the same file name", name)
+		}
+		raw, err := helmTemplate(u, values)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = raw
+		names = append(names, name)
+	}
+	if len(values.Workloads) == 0 {
+		values.Workloads = nil
+	}
+	files[HelmValuesFile] = values
+
+	for _, name := range names {
+		raw, ok := templates[name]
+		if !ok {
+			raw, err = yaml.Marshal(files[name])
+			if err != nil {
+				return nil, fmt.Errorf("unable to marshal %s: %v", name, err)
+			}
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, name), raw, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write %s: %v", name, err)
+		}
+	}
+	return names, nil
+}
+
+// helmNamespace will return the namespace of the first namespaced
+// object, which is exposed as the namespace value.
+func helmNamespace(objs []runtime.Object) string {
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		if accessor.GetNamespace() != "" {
+			return accessor.GetNamespace()
+		}
+	}
+	return ""
+}
+
+// helmTemplate will encode an object as a Helm template, replacing the
+// namespace, replicas and images with references to the values.
+//
+// The values of the object are added to values.
+func helmTemplate(u *unstructured.Unstructured, values *HelmValues) ([]byte, error) {
+	var replacements []string
+	placeholder := func(template string) string {
+		key := fmt.Sprintf("NAML_HELM_PLACEHOLDER_%d_", len(replacements)/2)
+		replacements = append(replacements, key, template)
+		return key
+	}
+
+	// [ Namespace ]
+	namespace := values.Namespace
+	if namespace != "" {
+		template := placeholder("{{ .Values.namespace | quote }}")
+		if u.GetNamespace() == namespace {
+			u.SetNamespace(template)
+		}
+		if u.GetKind() == "Namespace" && u.GetName() == namespace {
+			u.SetName(template)
+		}
+		subjects, _, _ := unstructured.NestedSlice(u.Object, "subjects")
+		for _, subject := range subjects {
+			if s, ok := subject.(map[string]interface{}); ok && s["namespace"] == namespace {
+				s["namespace"] = template
+			}
+		}
+		if subjects != nil {
+			err := unstructured.SetNestedSlice(u.Object, subjects, "subjects")
+			if err != nil {
+				return nil, fmt.Errorf("unable to set subjects of %s: %v", u.GetName(), err)
+			}
+		}
+	}
+
+	// [ Workloads ]
+	if path, ok := helmPodSpecPaths[u.GetKind()]; ok {
+		kind := strings.ToLower(u.GetKind())
+		name := u.GetName()
+		if _, ok := values.Workloads[kind][name]; ok {
+			return nil, fmt.Errorf("unable to add values for %s %s: more than one workload with the same kind and name", u.GetKind(), name)
+		}
+		workload := &HelmWorkload{
+			Images: make(map[string]string),
+		}
+		if replicas, ok, _ := unstructured.NestedInt64(u.Object, "spec", "replicas"); ok && u.GetKind() != "Pod" {
+			workload.Replicas = &replicas
+			template := placeholder(fmt.Sprintf("{{ index .Values.workloads %q %q \"replicas\" }}", kind, name))
+			u.Object["spec"].(map[string]interface{})["replicas"] = template
+		}
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, _, _ := unstructured.NestedSlice(u.Object, append(path, field)...)
+			for _, container := range containers {
+				c, ok := container.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				cname, _ := c["name"].(string)
+				image, _ := c["image"].(string)
+				if cname == "" || image == "" {
+					continue
+				}
+				workload.Images[cname] = image
+				c["image"] = placeholder(fmt.Sprintf("{{ index .Values.workloads %q %q \"images\" %q | quote }}", kind, name, cname))
+			}
+			if containers != nil {
+				err := unstructured.SetNestedSlice(u.Object, containers, append(path, field)...)
+				if err != nil {
+					return nil, fmt.Errorf("unable to set %s of %s: %v", field, name, err)
+				}
+			}
+		}
+		if values.Workloads[kind] == nil {
+			values.Workloads[kind] = make(map[string]*HelmWorkload)
+		}
+		values.Workloads[kind][name] = workload
+	}
+
+	raw, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal %s: %v", u.GetName(), err)
+	}
+	template := helmDelimiters.ReplaceAllStringFunc(string(raw), func(delimiter string) string {
+		return "{{`" + delimiter + "`}}"
+	})
+	return []byte(strings.NewReplacer(replacements...).Replace(template)), nil
+}
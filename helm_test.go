@@ -0,0 +1,193 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"text/template"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+type helmVersionApp struct {
+	testApp
+	version string
+}
+
+func (h *helmVersionApp) Meta() *AppMeta {
+	meta := h.testApp.Meta()
+	meta.ResourceVersion = h.version
+	return meta
+}
+
+func TestWriteHelmChartVersion(t *testing.T) {
+	for version, valid := range map[string]bool{
+		"1.2.3":            true,
+		"1.0.0-alpha.1+42": true,
+		"v1.2.3":           false,
+		"1.2":              false,
+		"123456":           false,
+		"01.2.3":           false,
+	} {
+		app := &helmVersionApp{version: version}
+		_, err := WriteHelmChart(app, t.TempDir())
+		if valid && err != nil {
+			t.Errorf("unexpected error for version %s: %v", version, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected error for version %s", version)
+		}
+	}
+}
+
+func TestWriteHelmChart(t *testing.T) {
+	deployment := BusyboxDeployment("example")
+	deployment.Namespace = "example"
+	deployment.Annotations = map[string]string{"template": "{{ .Name }}"}
+
+	// A workload of another kind with the same name
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "example"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "@daily",
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "example", Image: "busybox"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	app := &testApp{
+		objects: []runtime.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+			&rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "example"},
+				Subjects:   []rbacv1.Subject{{Kind: "ServiceAccount", Name: "example", Namespace: "example"}},
+			},
+			deployment,
+			cronjob,
+		},
+	}
+	dir := t.TempDir()
+	files, err := WriteHelmChart(app, dir)
+	if err != nil {
+		t.Fatalf("unable to write chart: %v", err)
+	}
+	if len(files) != 6 {
+		t.Fatalf("expected 6 files, got %v", files)
+	}
+
+	// [ Chart.yaml ]
+	chart := &HelmChart{}
+	readYAML(t, filepath.Join(dir, HelmChartFile), chart)
+	if chart.Name != "test-app" || chart.Version != HelmDefaultVersion {
+		t.Errorf("unexpected chart: %+v", chart)
+	}
+
+	// [ values.yaml ]
+	values := map[string]interface{}{}
+	readYAML(t, filepath.Join(dir, HelmValuesFile), &values)
+	workloads := values["workloads"].(map[string]interface{})
+	example := workloads["deployment"].(map[string]interface{})["example"].(map[string]interface{})
+	job := workloads["cronjob"].(map[string]interface{})["example"].(map[string]interface{})
+	if values["namespace"] != "example" || example["replicas"] != float64(1) {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	// Render every template with new values, like Helm would
+	values["namespace"] = "rendered"
+	example["replicas"] = 3
+	example["images"].(map[string]interface{})["example"] = "registry.example.com/busybox"
+	job["images"].(map[string]interface{})["example"] = "registry.example.com/job"
+	rendered := make(map[string]runtime.Object)
+	for _, file := range files[2:] {
+		raw, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", file, err)
+		}
+		tpl, err := template.New(file).Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(string(raw))
+		if err != nil {
+			t.Fatalf("unable to parse template %s: %v", file, err)
+		}
+		buf := &bytes.Buffer{}
+		err = tpl.Execute(buf, map[string]interface{}{"Values": values})
+		if err != nil {
+			t.Fatalf("unable to render template %s: %v", file, err)
+		}
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(buf.Bytes(), nil, nil)
+		if err != nil {
+			t.Fatalf("unable to decode rendered %s: %v\n%s", file, err, buf.String())
+		}
+		rendered[file] = obj
+	}
+
+	namespace := rendered["templates/namespace-example.yaml"].(*corev1.Namespace)
+	if namespace.Name != "rendered" {
+		t.Errorf("expected namespace rendered, got %s", namespace.Name)
+	}
+	binding := rendered["templates/clusterrolebinding-example.yaml"].(*rbacv1.ClusterRoleBinding)
+	if binding.Subjects[0].Namespace != "rendered" {
+		t.Errorf("expected subject namespace rendered, got %s", binding.Subjects[0].Namespace)
+	}
+	deployed := rendered["templates/deployment-example-example.yaml"].(*appsv1.Deployment)
+	if deployed.Namespace != "rendered" || *deployed.Spec.Replicas != 3 {
+		t.Errorf("unexpected deployment: %s replicas %d", deployed.Namespace, *deployed.Spec.Replicas)
+	}
+	if image := deployed.Spec.Template.Spec.Containers[0].Image; image != "registry.example.com/busybox" {
+		t.Errorf("unexpected image: %s", image)
+	}
+	if deployed.Annotations["template"] != "{{ .Name }}" {
+		t.Errorf("expected template delimiters to be escaped, got %q", deployed.Annotations["template"])
+	}
+	scheduled := rendered["templates/cronjob-example-example.yaml"].(*batchv1.CronJob)
+	if image := scheduled.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image; image != "registry.example.com/job" {
+		t.Errorf("unexpected cronjob image: %s", image)
+	}
+}
+
+func readYAML(t *testing.T, path string, v interface{}) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read %s: %v", path, err)
+	}
+	err = yaml.Unmarshal(raw, v)
+	if err != nil {
+		t.Fatalf("unable to unmarshal %s: %v", path, err)
+	}
+}
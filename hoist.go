@@ -0,0 +1,372 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// hoistMaxNameLength is the longest name generated from
+	// the values of a hoisted literal.
+	hoistMaxNameLength = 40
+)
+
+// hoisted is a literal that is repeated in the generated source code.
+type hoisted struct {
+	name        string
+	source      string
+	field       string
+	occurrences []*ast.CompositeLit
+
+	// fn is true for maps and slices, which are returned by a function
+	// instead of shared as a variable.
+	fn bool
+}
+
+// hoist will find identical literals that are repeated in the generated
+// source code, and define them once at the package level.
+//
+// Typed struct literals that are made entirely of constant values are
+// shared as a variable, because a struct is copied when it is assigned.
+// Maps and slices are references, so map[string]string and []string
+// literals are returned by a function instead, and every occurrence
+// calls the function to get its own copy. Otherwise a change to the
+// labels of a pod template would also change the selector.
+func hoist(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse generated code: %v", err)
+	}
+
+	// Every identifier in the file is reserved
+	reserved := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			reserved[ident.Name] = true
+		}
+		return true
+	})
+
+	// Find all candidate literals, and the field they are assigned to
+	candidates := make(map[string]*hoisted)
+	var keys []string
+	var field string
+	addressed := make(map[*ast.CompositeLit]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.UnaryExpr:
+			// Never share a literal that is addressed
+			if lit, ok := x.X.(*ast.CompositeLit); ok && x.Op == token.AND {
+				addressed[lit] = true
+			}
+		case *ast.KeyValueExpr:
+			if ident, ok := x.Key.(*ast.Ident); ok {
+				field = ident.Name
+			}
+		case *ast.CompositeLit:
+			if addressed[x] {
+				return true
+			}
+			key, ok := hoistKey(x)
+			if !ok {
+				return true
+			}
+			if _, ok := candidates[key]; !ok {
+				candidates[key] = &hoisted{
+					source: string(src[fset.Position(x.Pos()).Offset:fset.Position(x.End()).Offset]),
+					field:  field,
+				}
+				keys = append(keys, key)
+			}
+			candidates[key].occurrences = append(candidates[key].occurrences, x)
+			// Nested literals are part of this literal
+			return false
+		}
+		return true
+	})
+
+	// Only hoist literals that are repeated
+	var hoists []*hoisted
+	for _, key := range keys {
+		h := candidates[key]
+		if len(h.occurrences) < 2 {
+			continue
+		}
+		h.name = hoistName(h, reserved)
+		reserved[h.name] = true
+		_, isStruct := h.occurrences[0].Type.(*ast.SelectorExpr)
+		h.fn = !isStruct
+		hoists = append(hoists, h)
+	}
+	if len(hoists) == 0 {
+		return src, nil
+	}
+
+	// Replace every occurrence from the end of the file
+	// so that the offsets remain valid
+	type replacement struct {
+		start, end int
+		name       string
+	}
+	var replacements []replacement
+	for _, h := range hoists {
+		name := h.name
+		if h.fn {
+			name += "()"
+		}
+		for _, occurrence := range h.occurrences {
+			replacements = append(replacements, replacement{
+				start: fset.Position(occurrence.Pos()).Offset,
+				end:   fset.Position(occurrence.End()).Offset,
+				name:  name,
+			})
+		}
+	}
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].start > replacements[j].start
+	})
+	hoistedSrc := make([]byte, len(src))
+	copy(hoistedSrc, src)
+	for _, r := range replacements {
+		hoistedSrc = append(hoistedSrc[:r.start], append([]byte(r.name), hoistedSrc[r.end:]...)...)
+	}
+
+	// Define the variables and functions at the end of the file
+	sort.Slice(hoists, func(i, j int) bool {
+		return hoists[i].name < hoists[j].name
+	})
+	buf := bytes.NewBuffer(hoistedSrc)
+	var vars, fns []*hoisted
+	for _, h := range hoists {
+		if h.fn {
+			fns = append(fns, h)
+			continue
+		}
+		vars = append(vars, h)
+	}
+	if len(vars) > 0 {
+		buf.WriteString("\n// Values that are shared by more than one object\nvar (\n")
+		for _, h := range vars {
+			buf.WriteString(fmt.Sprintf("\t%s = %s\n", h.name, h.source))
+		}
+		buf.WriteString(")\n")
+	}
+	for _, h := range fns {
+		lit := h.occurrences[0]
+		typ := string(hoistedType(src, fset, lit))
+		buf.WriteString(fmt.Sprintf("\n// %s will return a new copy of a value that is used by more than one object\nfunc %s() %s {\n\treturn %s\n}\n", h.name, h.name, typ, h.source))
+	}
+	return buf.Bytes(), nil
+}
+
+// hoistedType will return the source code of the type of a literal.
+func hoistedType(src []byte, fset *token.FileSet, lit *ast.CompositeLit) []byte {
+	return src[fset.Position(lit.Type.Pos()).Offset:fset.Position(lit.Type.End()).Offset]
+}
+
+// hoistKey will return a canonical key for a literal that
+// is safe to hoist. Literals with the same key are identical.
+func hoistKey(lit *ast.CompositeLit) (string, bool) {
+	switch t := lit.Type.(type) {
+	case *ast.MapType:
+		if !hoistIsIdent(t.Key, "string") || !hoistIsIdent(t.Value, "string") || len(lit.Elts) == 0 {
+			return "", false
+		}
+		var pairs []string
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return "", false
+			}
+			k, ok := kv.Key.(*ast.BasicLit)
+			if !ok {
+				return "", false
+			}
+			v, ok := kv.Value.(*ast.BasicLit)
+			if !ok {
+				return "", false
+			}
+			pairs = append(pairs, fmt.Sprintf("%s:%s", k.Value, v.Value))
+		}
+		sort.Strings(pairs)
+		return fmt.Sprintf("map[string]string{%s}", strings.Join(pairs, ",")), true
+	case *ast.ArrayType:
+		if t.Len != nil || !hoistIsIdent(t.Elt, "string") || len(lit.Elts) < 2 {
+			return "", false
+		}
+		var values []string
+		for _, elt := range lit.Elts {
+			v, ok := elt.(*ast.BasicLit)
+			if !ok {
+				return "", false
+			}
+			values = append(values, v.Value)
+		}
+		return fmt.Sprintf("[]string{%s}", strings.Join(values, ",")), true
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok || len(lit.Elts) < 2 {
+			return "", false
+		}
+		// TypeMeta is repeated in every object, and is clearer inline
+		if t.Sel.Name == "TypeMeta" {
+			return "", false
+		}
+		var fields []string
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return "", false
+			}
+			k, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				return "", false
+			}
+			v, ok := hoistConstant(kv.Value)
+			if !ok {
+				return "", false
+			}
+			fields = append(fields, fmt.Sprintf("%s:%s", k.Name, v))
+		}
+		sort.Strings(fields)
+		return fmt.Sprintf("%s.%s{%s}", pkg.Name, t.Sel.Name, strings.Join(fields, ",")), true
+	}
+	return "", false
+}
+
+// hoistConstant will return a canonical value for a constant expression.
+func hoistConstant(expr ast.Expr) (string, bool) {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		return x.Value, true
+	case *ast.Ident:
+		if x.Name == "true" || x.Name == "false" {
+			return x.Name, true
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); ok {
+			return fmt.Sprintf("%s.%s", pkg.Name, x.Sel.Name), true
+		}
+	case *ast.CompositeLit:
+		if _, ok := x.Type.(*ast.SelectorExpr); ok {
+			return hoistKey(x)
+		}
+	}
+	return "", false
+}
+
+func hoistIsIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// hoistName will return a unique Go name for a hoisted literal
+//
+// Maps and slices are named after their values and the field they
+// are assigned to. Structs are named after their type.
+func hoistName(h *hoisted, reserved map[string]bool) string {
+	lit := h.occurrences[0]
+	var name string
+	switch t := lit.Type.(type) {
+	case *ast.SelectorExpr:
+		name = hoistCamel([]string{t.Sel.Name})
+		for _, elt := range lit.Elts {
+			kv := elt.(*ast.KeyValueExpr)
+			if kv.Key.(*ast.Ident).Name != "Name" {
+				continue
+			}
+			if v, ok := kv.Value.(*ast.BasicLit); ok {
+				name = hoistCamel([]string{hoistUnquote(v.Value), t.Sel.Name})
+			}
+		}
+	default:
+		suffix := h.field
+		switch suffix {
+		case "MatchLabels", "Selector":
+			suffix = "Labels"
+		case "":
+			suffix = "Values"
+		}
+		var words []string
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			words = append(words, hoistUnquote(elt.(*ast.BasicLit).Value))
+		}
+		name = hoistCamel(append(words, suffix))
+		if len(name) > hoistMaxNameLength {
+			name = hoistCamel([]string{suffix})
+		}
+	}
+	if name == "" || token.Lookup(name).IsKeyword() {
+		name = "shared"
+	}
+	unique := name
+	for i := 2; reserved[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	return unique
+}
+
+func hoistUnquote(value string) string {
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return value
+	}
+	return unquoted
+}
+
+// hoistCamel will join words into a lowerCamelCase Go identifier.
+func hoistCamel(words []string) string {
+	var name string
+	for _, word := range words {
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, part := range parts {
+			if name == "" {
+				// Identifiers can not start with a digit
+				if unicode.IsDigit(rune(part[0])) {
+					name = "v"
+				} else {
+					name = strings.ToLower(part[:1]) + part[1:]
+					continue
+				}
+			}
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"go/format"
+	"strings"
+	"testing"
+)
+
+const hoistTestSource = `package main
+
+func objects() []interface{} {
+	a := &Object{
+		Labels:   map[string]string{"app": "example", "tier": "web"},
+		Selector: map[string]string{"tier": "web", "app": "example"},
+		Verbs:    []string{"get", "list"},
+		Port:     &corev1.ServicePort{Name: "http", Port: 80},
+		Meta:     metav1.ObjectMeta{Name: "web", Namespace: "web"},
+	}
+	b := &Object{
+		Labels: map[string]string{"app": "other"},
+		Verbs:  []string{"get", "list"},
+		Port:   &corev1.ServicePort{Name: "http", Port: 80},
+		Meta:   metav1.ObjectMeta{Name: "web", Namespace: "web"},
+	}
+	return []interface{}{a, b}
+}
+`
+
+func TestHoist(t *testing.T) {
+	hoisted, err := hoist([]byte(hoistTestSource))
+	if err != nil {
+		t.Fatalf("unable to hoist: %v", err)
+	}
+	formatted, err := format.Source(hoisted)
+	if err != nil {
+		t.Fatalf("unable to format hoisted source: %v\n%s", err, string(hoisted))
+	}
+	src := string(formatted)
+	for _, expected := range []string{
+		"Labels:   exampleWebLabels(),",
+		"Selector: exampleWebLabels(),",
+		"Verbs:    getListVerbs(),",
+		"func exampleWebLabels() map[string]string {\n\treturn map[string]string{\"app\": \"example\", \"tier\": \"web\"}\n}",
+		"func getListVerbs() []string {\n\treturn []string{\"get\", \"list\"}\n}",
+		// Structs are copied when they are assigned, so they are shared
+		"Meta:     webObjectMeta,",
+		`webObjectMeta = metav1.ObjectMeta{Name: "web", Namespace: "web"}`,
+		// Single values and addressed literals are not hoisted
+		`Labels: map[string]string{"app": "other"},`,
+		`Port:     &corev1.ServicePort{Name: "http", Port: 80},`,
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("missing %q in hoisted source:\n%s", expected, src)
+		}
+	}
+}
+
+func TestHoistNothing(t *testing.T) {
+	src := []byte("package main\n\nvar x = map[string]string{\"app\": \"example\"}\n")
+	hoisted, err := hoist(src)
+	if err != nil {
+		t.Fatalf("unable to hoist: %v", err)
+	}
+	if string(hoisted) != string(src) {
+		t.Errorf("unexpected change: %s", string(hoisted))
+	}
+}
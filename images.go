@@ -0,0 +1,223 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Image is a container image used by an object.
+type Image struct {
+	App       string `json:"app,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Container string `json:"container"`
+
+	// Path is the field path of the image in the object.
+	// Example: spec.template.spec.initContainers[0].image
+	Path string `json:"path"`
+
+	Image string `json:"image"`
+}
+
+// Latest will return true if the image uses the latest tag,
+// either explicitly or because it has no tag or digest.
+func (i *Image) Latest() bool {
+	return ImageLatest(i.Image)
+}
+
+// ImageLatest will return true if an image reference uses the
+// latest tag, either explicitly or because it has no tag or digest.
+func ImageLatest(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	return i < 0 || name[i+1:] == "latest"
+}
+
+// PodSpec will return the pod spec of a workload and its field path,
+// or nil if the object does not have a pod spec.
+func PodSpec(obj runtime.Object) (*corev1.PodSpec, *field.Path) {
+	switch x := obj.(type) {
+	case *corev1.Pod:
+		return &x.Spec, field.NewPath("spec")
+	case *appsv1.Deployment:
+		return &x.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *appsv1.StatefulSet:
+		return &x.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *appsv1.DaemonSet:
+		return &x.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *batchv1.Job:
+		return &x.Spec.Template.Spec, field.NewPath("spec", "template", "spec")
+	case *batchv1.CronJob:
+		return &x.Spec.JobTemplate.Spec.Template.Spec, field.NewPath("spec", "jobTemplate", "spec", "template", "spec")
+	case *batchv1beta1.CronJob:
+		return &x.Spec.JobTemplate.Spec.Template.Spec, field.NewPath("spec", "jobTemplate", "spec", "template", "spec")
+	}
+	return nil, nil
+}
+
+// WalkImages will call fn for the image of every init container,
+// container and ephemeral container of a workload. The image can
+// be changed in place.
+func WalkImages(obj runtime.Object, fn func(container string, image *string, path *field.Path)) {
+	spec, path := PodSpec(obj)
+	if spec == nil {
+		return
+	}
+	for i := range spec.InitContainers {
+		c := &spec.InitContainers[i]
+		fn(c.Name, &c.Image, path.Child("initContainers").Index(i).Child("image"))
+	}
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		fn(c.Name, &c.Image, path.Child("containers").Index(i).Child("image"))
+	}
+	for i := range spec.EphemeralContainers {
+		c := &spec.EphemeralContainers[i]
+		fn(c.Name, &c.Image, path.Child("ephemeralContainers").Index(i).Child("image"))
+	}
+}
+
+// Images will return every container image of an application
+// in install order.
+//
+// The application must already be installed, either in Kubernetes
+// or "nowhere" with app.Install(nil).
+func Images(app Deployable) ([]*Image, error) {
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return nil, err
+	}
+	var images []*Image
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find metadata for %T: %v", obj, err)
+		}
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		WalkImages(obj, func(container string, image *string, path *field.Path) {
+			images = append(images, &Image{
+				App:       app.Meta().Name,
+				Kind:      kind,
+				Namespace: accessor.GetNamespace(),
+				Name:      accessor.GetName(),
+				Container: container,
+				Path:      path.String(),
+				Image:     *image,
+			})
+		})
+	}
+	return images, nil
+}
+
+// RunImages will find an application by name, install it "nowhere"
+// and return its Images.
+func RunImages(appName string) ([]*Image, error) {
+	app := Find(appName)
+	if app == nil {
+		return nil, fmt.Errorf("unable to find app: %s", appName)
+	}
+
+	err := Configure(app)
+	if err != nil {
+		return nil, err
+	}
+
+	// Install the application "nowhere" to register the components in memory
+	err = app.Install(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to install app in memory: %v", err)
+	}
+	return Images(app)
+}
+
+// PrintImages will write images to w as a table, or as JSON.
+func PrintImages(w io.Writer, images []*Image, o OutputEncoding) error {
+	switch o {
+	case OutputJSON:
+		if images == nil {
+			images = []*Image{}
+		}
+		raw, err := json.MarshalIndent(images, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to JSON marshal: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+	case OutputJSONLines:
+		for _, image := range images {
+			raw, err := json.Marshal(image)
+			if err != nil {
+				return fmt.Errorf("unable to JSON marshal: %v", err)
+			}
+			_, err = fmt.Fprintln(w, string(raw))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMAGE\tAPP\tOBJECT\tPATH")
+	for _, image := range images {
+		object := fmt.Sprintf("%s/%s", image.Kind, image.Name)
+		if image.Namespace != "" {
+			object = fmt.Sprintf("%s/%s/%s", image.Kind, image.Namespace, image.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", image.Image, image.App, object, image.Path)
+	}
+	return tw.Flush()
+}
+
+// LatestImages will return the images that use the latest tag,
+// sorted and without duplicates.
+func LatestImages(images []*Image) []string {
+	seen := make(map[string]bool)
+	var latest []string
+	for _, image := range images {
+		if image.Latest() && !seen[image.Image] {
+			seen[image.Image] = true
+			latest = append(latest, image.Image)
+		}
+	}
+	sort.Strings(latest)
+	return latest
+}
@@ -0,0 +1,115 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestImages(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "busybox:1.34"}},
+			Containers:     []corev1.Container{{Name: "app", Image: "nginx@sha256:abc"}},
+			EphemeralContainers: []corev1.EphemeralContainer{{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug", Image: "registry.example.com:5000/debug"},
+			}},
+		},
+	}
+	cronjob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup"},
+	}
+	cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "backup", Image: "backup:latest"}}
+	app := &testApp{
+		objects: []runtime.Object{
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+			pod,
+			cronjob,
+			BusyboxDeployment("example"),
+		},
+	}
+	images, err := Images(app)
+	if err != nil {
+		t.Fatalf("unable to find images: %v", err)
+	}
+	var actual []string
+	for _, image := range images {
+		actual = append(actual, image.Kind+" "+image.Path+" "+image.Image)
+	}
+	expected := []string{
+		"Pod spec.initContainers[0].image busybox:1.34",
+		"Pod spec.containers[0].image nginx@sha256:abc",
+		"Pod spec.ephemeralContainers[0].image registry.example.com:5000/debug",
+		"CronJob spec.jobTemplate.spec.template.spec.containers[0].image backup:latest",
+		"Deployment spec.template.spec.containers[0].image busybox",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images:\n%v\ngot:\n%v", expected, actual)
+	}
+
+	latest := LatestImages(images)
+	if !reflect.DeepEqual(latest, []string{"backup:latest", "busybox", "registry.example.com:5000/debug"}) {
+		t.Errorf("unexpected latest images: %v", latest)
+	}
+
+	buf := &bytes.Buffer{}
+	err = PrintImages(buf, images, OutputJSON)
+	if err != nil {
+		t.Fatalf("unable to print images: %v", err)
+	}
+	var decoded []*Image
+	err = json.Unmarshal(buf.Bytes(), &decoded)
+	if err != nil {
+		t.Fatalf("unable to decode JSON: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, images) {
+		t.Errorf("JSON images do not match")
+	}
+}
+
+func TestImageLatest(t *testing.T) {
+	for image, expected := range map[string]bool{
+		"nginx":                                 true,
+		"nginx:latest":                          true,
+		"nginx:1.21":                            false,
+		"docker.io/library/nginx":               true,
+		"localhost:5000/nginx":                  true,
+		"localhost:5000/nginx:1.21":             false,
+		"nginx@sha256:abc":                      false,
+		"quay.io/example/app:latest@sha256:abc": false,
+	} {
+		if actual := ImageLatest(image); actual != expected {
+			t.Errorf("expected ImageLatest(%q) = %v", image, expected)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+
+	"github.com/kris-nova/naml/codify"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// namespaceInit will move every namespaced object to the namespace of the
+// -n/--namespace flag, after every other transformer.
+func namespaceInit(namespace string) {
+	if namespace == "" {
+		return
+	}
+	AddTransformers(NamespaceTransformer(namespace))
+}
+
+// clusterScopedKinds are the built in kinds that do not have a namespace,
+// and the kinds registered with RegisterClusterScoped.
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Group: "", Kind: "ComponentStatus"}:                                            true,
+	{Group: "", Kind: "Namespace"}:                                                  true,
+	{Group: "", Kind: "Node"}:                                                       true,
+	{Group: "", Kind: "PersistentVolume"}:                                           true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}: true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:               true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                           true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:               true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                     true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:     true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                              true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                    true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                    true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                       true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                             true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                    true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                 true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                             true,
+}
+
+// RegisterClusterScoped will register kinds that do not have a namespace,
+// such as the cluster scoped custom resources of an operator.
+//
+//	naml.RegisterClusterScoped(schema.GroupKind{Group: "cert-manager.io", Kind: "ClusterIssuer"})
+//
+// The kinds of a CustomResourceDefinition in the same application, and
+// the kinds registered with codify, do not have to be registered.
+func RegisterClusterScoped(gks ...schema.GroupKind) {
+	for _, gk := range gks {
+		clusterScopedKinds[gk] = true
+	}
+}
+
+// ClusterScoped will return true if the object does not have a namespace.
+//
+// The scope of a kind is found from the built in kinds, the kinds registered
+// with RegisterClusterScoped and the codifiers registered with codify.
+func ClusterScoped(obj runtime.Object) bool {
+	return clusterScoped(obj, nil)
+}
+
+// clusterScoped is ClusterScoped, and also uses the scopes of the
+// CustomResourceDefinitions of an application from crdScopes.
+func clusterScoped(obj runtime.Object, crds map[schema.GroupKind]bool) bool {
+	gvk, err := ObjectKind(obj)
+	if err != nil {
+		return false
+	}
+	if cluster, ok := crds[gvk.GroupKind()]; ok {
+		return cluster
+	}
+	if clusterScopedKinds[gvk.GroupKind()] {
+		return true
+	}
+	if c := codify.Lookup(gvk); c != nil {
+		return c.ClusterScoped
+	}
+	return false
+}
+
+// crdScopes will return the kinds of every CustomResourceDefinition in objs,
+// which are true when the kind is cluster scoped.
+func crdScopes(objs []runtime.Object) map[schema.GroupKind]bool {
+	scopes := make(map[schema.GroupKind]bool)
+	for _, obj := range objs {
+		switch x := obj.(type) {
+		case *apiextensionsv1.CustomResourceDefinition:
+			gk := schema.GroupKind{Group: x.Spec.Group, Kind: x.Spec.Names.Kind}
+			scopes[gk] = x.Spec.Scope == apiextensionsv1.ClusterScoped
+		case *unstructured.Unstructured:
+			if x.GroupVersionKind().GroupKind() != apiextensionsv1.Kind("CustomResourceDefinition") {
+				continue
+			}
+			group, _, _ := unstructured.NestedString(x.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(x.Object, "spec", "names", "kind")
+			scope, _, _ := unstructured.NestedString(x.Object, "spec", "scope")
+			scopes[schema.GroupKind{Group: group, Kind: kind}] = scope == string(apiextensionsv1.ClusterScoped)
+		}
+	}
+	return scopes
+}
+
+// NamespaceTransformer will move every namespaced object to a namespace.
+//
+// Namespace objects for the namespaces of the application are renamed,
+// and ServiceAccount subjects in those namespaces are moved. When more
+// than one Namespace object is renamed only the first one is kept.
+//
+// It is an error when two objects have the same kind and name after
+// they are moved, such as a ConfigMap with the same name in two
+// namespaces of the application.
+func NamespaceTransformer(namespace string) Transformer {
+	return transformerFunc(func(objs []runtime.Object) ([]runtime.Object, error) {
+		crds := crdScopes(objs)
+		moved := make(map[string]bool)
+		for _, obj := range objs {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, err
+			}
+			if clusterScoped(obj, crds) {
+				continue
+			}
+			moved[accessor.GetNamespace()] = true
+			accessor.SetNamespace(namespace)
+		}
+		var transformed []runtime.Object
+		hasNamespace := false
+		for _, obj := range objs {
+			switch x := obj.(type) {
+			case *corev1.Namespace:
+				if moved[x.Name] || x.Name == namespace {
+					if hasNamespace {
+						continue
+					}
+					x.Name = namespace
+					hasNamespace = true
+				}
+			case *rbacv1.RoleBinding:
+				moveSubjects(x.Subjects, moved, namespace)
+			case *rbacv1.ClusterRoleBinding:
+				moveSubjects(x.Subjects, moved, namespace)
+			}
+			transformed = append(transformed, obj)
+		}
+		err := namespaceCollisions(transformed, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return transformed, nil
+	})
+}
+
+// namespaceCollisions will return an error naming the first two objects
+// with the same kind, namespace and name.
+func namespaceCollisions(objs []runtime.Object, namespace string) error {
+	seen := make(map[string]bool)
+	for _, obj := range objs {
+		gvk, err := ObjectKind(obj)
+		if err != nil {
+			return err
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s %s/%s", gvk.GroupKind().String(), accessor.GetNamespace(), accessor.GetName())
+		if seen[key] {
+			return fmt.Errorf("unable to move objects to namespace %s: more than one %s", namespace, key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func moveSubjects(subjects []rbacv1.Subject, moved map[string]bool, namespace string) {
+	for i := range subjects {
+		if subjects[i].Kind == rbacv1.ServiceAccountKind && moved[subjects[i].Namespace] {
+			subjects[i].Namespace = namespace
+		}
+	}
+}
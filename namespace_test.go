@@ -0,0 +1,173 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNamespaceTransformer(t *testing.T) {
+	SetTransformers(NamespaceTransformer("production"))
+	defer SetTransformers()
+
+	app := transformApp()
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	if name := objs[0].(*corev1.Namespace).Name; name != "production" {
+		t.Errorf("expected Namespace to be renamed, got %s", name)
+	}
+	if ns := objs[3].(*rbacv1.ClusterRole).Namespace; ns != "" {
+		t.Errorf("expected cluster scoped object without namespace, got %s", ns)
+	}
+	subjects := objs[4].(*rbacv1.ClusterRoleBinding).Subjects
+	if subjects[0].Namespace != "production" || subjects[1].Namespace != "kube-system" {
+		t.Errorf("unexpected subjects: %v", subjects)
+	}
+	if ns := objs[5].(*appsv1.Deployment).Namespace; ns != "production" {
+		t.Errorf("expected deployment in production, got %s", ns)
+	}
+
+	// The objects of the application are not changed
+	if ns := app.objects[5].(*appsv1.Deployment).Namespace; ns != "web" {
+		t.Errorf("expected original deployment in web, got %s", ns)
+	}
+}
+
+func TestNamespaceInit(t *testing.T) {
+	SetTransformers()
+	defer SetTransformers()
+
+	namespaceInit("")
+	if Transforming() {
+		t.Fatalf("expected no transformers without a namespace")
+	}
+	namespaceInit("staging")
+	objs, err := TransformedObjects(transformApp())
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatalf("unable to access object: %v", err)
+		}
+		ns := accessor.GetNamespace()
+		if ClusterScoped(obj) {
+			if ns != "" {
+				t.Errorf("expected cluster scoped %T without namespace, got %s", obj, ns)
+			}
+			continue
+		}
+		if ns != "staging" {
+			t.Errorf("expected %T in staging, got %s", obj, ns)
+		}
+	}
+}
+
+func TestNamespaceTransformerNamespaces(t *testing.T) {
+	web := BusyboxDeployment("web")
+	web.Namespace = "web"
+	api := BusyboxDeployment("api")
+	api.Namespace = "api"
+	objs := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "api"}},
+		web,
+		api,
+	}
+	objs, err := NamespaceTransformer("production").Transform(objs)
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	if len(objs) != 3 || objs[0].(*corev1.Namespace).Name != "production" {
+		t.Errorf("expected a single production Namespace, got %v", objs)
+	}
+
+	// Objects with the same name in two namespaces collide
+	first := BusyboxDeployment("web")
+	first.Namespace = "web"
+	second := BusyboxDeployment("web")
+	second.Namespace = "api"
+	_, err = NamespaceTransformer("production").Transform([]runtime.Object{first, second})
+	if err == nil || !strings.Contains(err.Error(), "production/web") {
+		t.Errorf("expected error for objects that collide, got %v", err)
+	}
+}
+
+func TestClusterScopedCustomResources(t *testing.T) {
+	custom := func(group, kind string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(group + "/v1")
+		u.SetKind(kind)
+		u.SetName("example")
+		u.SetNamespace("example")
+		return u
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "clusterwidgets.example.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "ClusterWidget"},
+			Scope: apiextensionsv1.ClusterScoped,
+		},
+	}
+	issuer := custom("cert-manager.io", "ClusterIssuer")
+	widget := custom("example.com", "ClusterWidget")
+	certificate := custom("cert-manager.io", "Certificate")
+	RegisterClusterScoped(schema.GroupKind{Group: "cert-manager.io", Kind: "ClusterIssuer"})
+	defer delete(clusterScopedKinds, schema.GroupKind{Group: "cert-manager.io", Kind: "ClusterIssuer"})
+
+	// A registered kind, and the kind of a CustomResourceDefinition of the app, are cluster scoped
+	objs, err := NamespaceTransformer("production").Transform([]runtime.Object{crd, issuer, widget, certificate})
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	if issuer.GetNamespace() != "example" || widget.GetNamespace() != "example" {
+		t.Errorf("expected cluster scoped custom resources to keep their namespace: %s %s", issuer.GetNamespace(), widget.GetNamespace())
+	}
+	if certificate.GetNamespace() != "production" {
+		t.Errorf("expected namespaced custom resource in production, got %s", certificate.GetNamespace())
+	}
+	if len(objs) != 4 {
+		t.Errorf("expected 4 objects, got %d", len(objs))
+	}
+
+	// Kinds are matched with their group
+	if ClusterScoped(custom("example.com", "ClusterRole")) {
+		t.Errorf("expected a ClusterRole of another group to be namespaced")
+	}
+}
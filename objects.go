@@ -0,0 +1,153 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// mappers are the cached discovery RESTMappers of each client.
+var (
+	mappers    = make(map[kubernetes.Interface]*restmapper.DeferredDiscoveryRESTMapper)
+	mappersMtx sync.Mutex
+)
+
+// Create will create a single runtime.Object of any kind in Kubernetes,
+// including custom resources and the kinds registered with codify.
+//
+// The resource of the kind is found with the discovery API of the client,
+// and the object is sent as JSON with the REST client of the discovery
+// client. This is the same library call that codify generates for each
+// object in function mode.
+func Create(client kubernetes.Interface, obj runtime.Object) error {
+	u, err := outputUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	rc, resourcePath, err := objectPath(client, u)
+	if err != nil {
+		return err
+	}
+	raw, err := u.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("unable to encode %s %s: %v", u.GetKind(), u.GetName(), err)
+	}
+	err = rc.Post().
+		AbsPath(resourcePath).
+		SetHeader("Content-Type", "application/json").
+		Body(raw).
+		Do(context.TODO()).
+		Error()
+	if err != nil {
+		return fmt.Errorf("unable to create %s %s: %v", u.GetKind(), u.GetName(), err)
+	}
+	return nil
+}
+
+// Delete will delete a single runtime.Object of any kind in Kubernetes,
+// like Create.
+//
+// Objects are deleted by name, and the rest of the object is ignored.
+func Delete(client kubernetes.Interface, obj runtime.Object) error {
+	u, err := outputUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	rc, resourcePath, err := objectPath(client, u)
+	if err != nil {
+		return err
+	}
+	err = rc.Delete().
+		AbsPath(resourcePath, u.GetName()).
+		Do(context.TODO()).
+		Error()
+	if err != nil {
+		return fmt.Errorf("unable to delete %s %s: %v", u.GetKind(), u.GetName(), err)
+	}
+	return nil
+}
+
+// objectPath will return the REST client of a client, and the path of
+// the resource of an object such as /apis/apps/v1/namespaces/default/deployments.
+//
+// Namespaced objects without a namespace are in the default namespace.
+func objectPath(client kubernetes.Interface, u *unstructured.Unstructured) (rest.Interface, string, error) {
+	if client == nil || client.Discovery() == nil || client.Discovery().RESTClient() == nil {
+		return nil, "", fmt.Errorf("unable to find %s %s: client does not have a REST client", u.GetKind(), u.GetName())
+	}
+	mapping, err := objectMapping(client, u)
+	if err != nil {
+		return nil, "", err
+	}
+	gvr := mapping.Resource
+	prefix := []string{"/apis", gvr.Group, gvr.Version}
+	if gvr.Group == "" {
+		prefix = []string{"/api", gvr.Version}
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		namespace := u.GetNamespace()
+		if namespace == "" {
+			namespace = "default"
+		}
+		prefix = append(prefix, "namespaces", namespace)
+	}
+	return client.Discovery().RESTClient(), path.Join(append(prefix, gvr.Resource)...), nil
+}
+
+// objectMapping will find the resource and scope of an object with the
+// discovery API of a client.
+//
+// The discovery cache is reset when the kind is not found, so the kinds
+// of a CustomResourceDefinition created earlier are found.
+func objectMapping(client kubernetes.Interface, u *unstructured.Unstructured) (*meta.RESTMapping, error) {
+	mappersMtx.Lock()
+	mapper, ok := mappers[client]
+	if !ok {
+		mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client.Discovery()))
+		mappers[client] = mapper
+	}
+	mappersMtx.Unlock()
+
+	gvk := u.GroupVersionKind()
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		mapper.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the resource of %s %s: %v", gvk.String(), u.GetName(), err)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,205 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/kris-nova/naml/codify"
+)
+
+// testAPIServer is a Kubernetes API with discovery for a few kinds,
+// which records every request that is not a discovery request.
+type testAPIServer struct {
+	*httptest.Server
+	requests []string
+	mtx      sync.Mutex
+}
+
+func newTestAPIServer(t *testing.T) (*testAPIServer, kubernetes.Interface) {
+	resources := map[string]*metav1.APIResourceList{
+		"/api/v1": {GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "namespaces", Kind: "Namespace", Verbs: metav1.Verbs{"create", "delete"}},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: metav1.Verbs{"create", "delete"}},
+		}},
+		"/apis/apps/v1": {GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true, Verbs: metav1.Verbs{"create", "delete"}},
+		}},
+		"/apis/apiextensions.k8s.io/v1": {GroupVersion: "apiextensions.k8s.io/v1", APIResources: []metav1.APIResource{
+			{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition", Verbs: metav1.Verbs{"create", "delete"}},
+		}},
+		"/apis/example.com/v1": {GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+			{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: metav1.Verbs{"create", "delete"}},
+			{Name: "clusterwidgets", Kind: "ClusterWidget", Verbs: metav1.Verbs{"create", "delete"}},
+			{Name: "gizmos", Kind: "Gizmo", Namespaced: true, Verbs: metav1.Verbs{"create", "delete"}},
+		}},
+	}
+	groups := &metav1.APIGroupList{}
+	for _, gv := range []string{"apps/v1", "apiextensions.k8s.io/v1", "example.com/v1"} {
+		group := gv[:len(gv)-len("/v1")]
+		version := metav1.GroupVersionForDiscovery{GroupVersion: gv, Version: "v1"}
+		groups.Groups = append(groups.Groups, metav1.APIGroup{Name: group, Versions: []metav1.GroupVersionForDiscovery{version}, PreferredVersion: version})
+	}
+
+	server := &testAPIServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var response interface{}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api":
+			response = &metav1.APIVersions{Versions: []string{"v1"}}
+		case r.Method == http.MethodGet && r.URL.Path == "/apis":
+			response = groups
+		case r.Method == http.MethodGet && resources[r.URL.Path] != nil:
+			response = resources[r.URL.Path]
+		case r.Method == http.MethodPost || r.Method == http.MethodDelete:
+			server.mtx.Lock()
+			server.requests = append(server.requests, r.Method+" "+r.URL.Path)
+			server.mtx.Unlock()
+			response = &metav1.Status{Status: metav1.StatusSuccess}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			response = &metav1.Status{Status: metav1.StatusFailure, Reason: metav1.StatusReasonNotFound, Code: http.StatusNotFound}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	return server, client
+}
+
+func TestCreateDelete(t *testing.T) {
+	server, client := newTestAPIServer(t)
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("example.com/v1")
+	widget.SetKind("Widget")
+	widget.SetName("example")
+	widget.SetNamespace("example")
+	clusterWidget := &unstructured.Unstructured{}
+	clusterWidget.SetAPIVersion("example.com/v1")
+	clusterWidget.SetKind("ClusterWidget")
+	clusterWidget.SetName("example")
+	for _, obj := range []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+		BusyboxDeployment("example"),
+		widget,
+		clusterWidget,
+	} {
+		err := Create(client, obj)
+		if err != nil {
+			t.Fatalf("unable to create %T: %v", obj, err)
+		}
+		err = Delete(client, obj)
+		if err != nil {
+			t.Fatalf("unable to delete %T: %v", obj, err)
+		}
+	}
+	expected := []string{
+		"POST /api/v1/namespaces",
+		"DELETE /api/v1/namespaces/example",
+		"POST /apis/apps/v1/namespaces/default/deployments",
+		"DELETE /apis/apps/v1/namespaces/default/deployments/example",
+		"POST /apis/example.com/v1/namespaces/example/widgets",
+		"DELETE /apis/example.com/v1/namespaces/example/widgets/example",
+		"POST /apis/example.com/v1/clusterwidgets",
+		"DELETE /apis/example.com/v1/clusterwidgets/example",
+	}
+	if len(server.requests) != len(expected) {
+		t.Fatalf("expected requests %v, got %v", expected, server.requests)
+	}
+	for i := range expected {
+		if server.requests[i] != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], server.requests[i])
+		}
+	}
+
+	// Kinds that the API does not serve are an error
+	unknown := &unstructured.Unstructured{}
+	unknown.SetAPIVersion("example.com/v1")
+	unknown.SetKind("Gadget")
+	unknown.SetName("example")
+	err := Create(client, unknown)
+	if err == nil {
+		t.Errorf("expected error for a kind that is not served")
+	}
+}
+
+var testGizmoGVK = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gizmo"}
+
+// testGizmo is a Go type for a kind that is registered with codify.
+type testGizmo struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Size              int `json:"size"`
+}
+
+func (g *testGizmo) DeepCopyObject() runtime.Object {
+	c := *g
+	g.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+	return &c
+}
+
+func TestCreateRegisteredKind(t *testing.T) {
+	err := codify.RegisterAndError(&codify.Codifier{
+		GroupVersionKind: testGizmoGVK,
+		AddToScheme: func(s *runtime.Scheme) error {
+			s.AddKnownTypeWithName(testGizmoGVK, &testGizmo{})
+			return nil
+		},
+		ImportPath: "github.com/example/operator/api/v1",
+		Alias:      "examplev1",
+		Factory: func(obj runtime.Object) (codify.Object, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to register: %v", err)
+	}
+
+	// The typed object of a registered kind is created like any other kind
+	server, client := newTestAPIServer(t)
+	err = Create(client, &testGizmo{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "example"}, Size: 3})
+	if err != nil {
+		t.Fatalf("unable to create registered kind: %v", err)
+	}
+	if len(server.requests) != 1 || server.requests[0] != "POST /apis/example.com/v1/namespaces/example/gizmos" {
+		t.Errorf("unexpected requests: %v", server.requests)
+	}
+}
@@ -0,0 +1,103 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+//go:build ignore
+// +build ignore
+
+// generate will write the embedded OpenAPI schema from the
+// swagger.json of a Kubernetes release, without the paths and
+// descriptions that naml validate does not use.
+//
+//	go run openapi/generate.go swagger.json openapi/kubernetes-v1.22.0.json.gz
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Println("usage: go run openapi/generate.go <swagger.json> <output.json.gz>")
+		os.Exit(1)
+	}
+	raw, err := ioutil.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	spec := map[string]interface{}{}
+	err = json.Unmarshal(raw, &spec)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	raw, err = json.Marshal(strip(map[string]interface{}{
+		"swagger":     spec["swagger"],
+		"info":        spec["info"],
+		"definitions": spec["definitions"],
+	}))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	f, err := os.Create(os.Args[2])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	w, _ := gzip.NewWriterLevel(f, gzip.BestCompression)
+	defer w.Close()
+	_, err = w.Write(raw)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// strip will remove every description from a schema, but
+// not the properties or definitions that are named description.
+func strip(schema interface{}) interface{} {
+	switch x := schema.(type) {
+	case []interface{}:
+		for i := range x {
+			x[i] = strip(x[i])
+		}
+	case map[string]interface{}:
+		delete(x, "description")
+		for key, value := range x {
+			if named, ok := value.(map[string]interface{}); ok && (key == "properties" || key == "definitions") {
+				for name, s := range named {
+					named[name] = strip(s)
+				}
+				continue
+			}
+			x[key] = strip(value)
+		}
+	}
+	return schema
+}
@@ -24,88 +24,270 @@
 package naml
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
-	"sigs.k8s.io/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sjson "k8s.io/apimachinery/pkg/runtime/serializer/json"
 )
 
 const (
-	OutputYAML OutputEncoding = 0
-	OutputJSON OutputEncoding = 1
+	OutputYAML      OutputEncoding = 0
+	OutputJSON      OutputEncoding = 1
+	OutputHelm      OutputEncoding = 2
+	OutputJSONLines OutputEncoding = 3
 )
 
 type OutputEncoding int
 
-func RunOutput(appName string, o OutputEncoding) error {
-	app := Find(appName)
-	if app == nil {
-		return fmt.Errorf("unable to find app: %s", appName)
+// ParseOutputEncoding will return the OutputEncoding for
+// a name such as yaml, json, jsonl or helm.
+func ParseOutputEncoding(name string) (OutputEncoding, error) {
+	switch strings.ToLower(name) {
+	case "", "yaml", "yml":
+		return OutputYAML, nil
+	case "json":
+		return OutputJSON, nil
+	case "jsonl", "jsonlines":
+		return OutputJSONLines, nil
+	case "helm":
+		return OutputHelm, nil
 	}
+	return OutputYAML, fmt.Errorf("unknown output encoding: %s (yaml, json, jsonl, helm)", name)
+}
 
-	// Install the application "nowhere" to register the components in memory
-	app.Install(nil)
+// RenderOptions are the options used to Render an application.
+type RenderOptions struct {
 
-	switch o {
+	// Encoding is the encoding of the objects.
+	Encoding OutputEncoding
 
-	// ---- [ JSON ] ----
-	case OutputJSON:
-		return PrintJSON(app)
+	// List will wrap the objects in a single v1.List.
+	List bool
+}
 
-	// ---- [ YAML ] ----
-	case OutputYAML:
-		return PrintKubeYAML(app)
+func RunOutput(appName string, o OutputEncoding) error {
+	return RunRender(os.Stdout, appName, &RenderOptions{Encoding: o})
+}
 
-	// ---- [ DEFAULT ] ----
-	default:
-		return PrintKubeYAML(app)
+// RunRender will find an application by name, install it "nowhere"
+// and Render it to w.
+func RunRender(w io.Writer, appName string, opts *RenderOptions) error {
+	return RunRenderApps(w, []string{appName}, opts)
+}
+
+// RunRenderApps will find every application in names, install them
+// "nowhere" and render the objects of all of them to w as a single
+// document, so a JSON array or a v1.List covers every app.
+func RunRenderApps(w io.Writer, names []string, opts *RenderOptions) error {
+	var objs []runtime.Object
+	err := transformApps(names, func(appName string) error {
+		app := Find(appName)
+		if app == nil {
+			return fmt.Errorf("unable to find app: %s", appName)
+		}
+
+		err := Configure(app)
+		if err != nil {
+			return err
+		}
+
+		// Install the application "nowhere" to register the components in memory
+		err = app.Install(nil)
+		if err != nil {
+			return fmt.Errorf("unable to install app in memory: %v", err)
+		}
+		appObjs, err := TransformedObjects(app)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, appObjs...)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return RenderObjects(w, objs, opts)
 }
 
-func PrintKubeYAML(app Deployable) error {
-	for i, obj := range app.Objects() {
-		raw, err := yaml.Marshal(obj)
+// Render will encode every object of an application to w.
+//
+// The application must already be installed, either in Kubernetes
+// or "nowhere" with app.Install(nil). A nil opts will render YAML.
+func Render(w io.Writer, app Deployable, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return err
+	}
+	return RenderObjects(w, objs, opts)
+}
+
+// RenderObjects will encode objects to w, like Render.
+func RenderObjects(w io.Writer, objs []runtime.Object, opts *RenderOptions) error {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	var us []*unstructured.Unstructured
+	for _, obj := range objs {
+		u, err := outputUnstructured(obj)
 		if err != nil {
-			return fmt.Errorf("unable to YAML marshal: %v", err)
+			return err
 		}
-		lines := strings.Split(string(raw), `
-`)
-		for _, line := range lines {
-
-			// And we are back to the fucking alias hackery again
-			// I am going to open an issue to clean this up once
-			// we have figured out all the crap we need to do
-			line = strings.ReplaceAll(line, "corev1", "v1")
-			line = strings.ReplaceAll(line, "rbacv1", "v1")
-			line = strings.ReplaceAll(line, "metav1", "v1")
-			line = strings.ReplaceAll(line, "appsv1", "v1")
-			line = strings.ReplaceAll(line, "corev1", "v1")
-
-			// Remove creationTimestamp
-			if strings.Contains(line, "creationTimestamp") {
-				continue
+		us = append(us, u)
+	}
+	if opts.List {
+		list := &unstructured.UnstructuredList{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "List",
+			},
+		}
+		for _, u := range us {
+			list.Items = append(list.Items, *u)
+		}
+		raw, err := encodeUnstructured(list, opts.Encoding)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(raw)
+		return err
+	}
+
+	switch opts.Encoding {
+
+	// ---- [ JSON ] ----
+	case OutputJSON:
+		var items []map[string]interface{}
+		for _, u := range us {
+			items = append(items, u.Object)
+		}
+		raw, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to JSON marshal: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(raw))
+		return err
+
+	// ---- [ HELM ] ----
+	case OutputHelm:
+		return fmt.Errorf("helm output is a chart directory: use --dir")
+
+	// ---- [ YAML and JSON lines ] ----
+	default:
+		for i, u := range us {
+			raw, err := encodeUnstructured(u, opts.Encoding)
+			if err != nil {
+				return err
 			}
-			// Ignore status for each object
-			if strings.Contains(line, "status") {
-				break
+			if i > 0 && opts.Encoding != OutputJSONLines {
+				_, err = fmt.Fprintln(w, strings.TrimSpace(YAMLDelimiter))
+				if err != nil {
+					return err
+				}
+			}
+			_, err = w.Write(raw)
+			if err != nil {
+				return err
 			}
-			fmt.Println(line)
-		}
-		if i < len(app.Objects())-1 {
-			fmt.Println(YAMLDelimiter)
-			fmt.Println()
 		}
+		return nil
 	}
-	return nil
 }
 
+// PrintKubeYAML will print every object as multi document YAML
+// with the Kubernetes serializer.
+func PrintKubeYAML(app Deployable) error {
+	return Render(os.Stdout, app, &RenderOptions{Encoding: OutputYAML})
+}
+
+// PrintJSON will print every object as a JSON array
+// with the apiVersion and kind set.
 func PrintJSON(app Deployable) error {
-	raw, err := json.MarshalIndent(app.Objects(), " ", "	")
+	return Render(os.Stdout, app, &RenderOptions{Encoding: OutputJSON})
+}
+
+// KubeYAML will encode an object as YAML with the Kubernetes serializer.
+//
+// The apiVersion and kind are set from the Scheme, and the status
+// and every creationTimestamp are removed.
+func KubeYAML(obj runtime.Object) ([]byte, error) {
+	u, err := outputUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return encodeUnstructured(u, OutputYAML)
+}
+
+// KubeJSON will encode an object as indented JSON with the Kubernetes
+// serializer, the same way as KubeYAML.
+func KubeJSON(obj runtime.Object) ([]byte, error) {
+	u, err := outputUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return encodeUnstructured(u, OutputJSON)
+}
+
+// encodeUnstructured will encode an object with the Kubernetes serializer.
+// JSON is indented, and JSON lines are written on a single line.
+func encodeUnstructured(obj runtime.Object, o OutputEncoding) ([]byte, error) {
+	options := k8sjson.SerializerOptions{}
+	switch o {
+	case OutputJSON:
+		options.Pretty = true
+	case OutputJSONLines:
+	default:
+		options.Yaml = true
+	}
+	serializer := k8sjson.NewSerializerWithOptions(k8sjson.DefaultMetaFactory, nil, nil, options)
+	buf := &bytes.Buffer{}
+	err := serializer.Encode(obj, buf)
 	if err != nil {
-		return fmt.Errorf("unable to JSON marshal: %v", err)
+		return nil, fmt.Errorf("unable to encode %s: %v", obj.GetObjectKind().GroupVersionKind().Kind, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// outputUnstructured will convert a copy of the object to an
+// *unstructured.Unstructured that is ready to be applied.
+func outputUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	obj = obj.DeepCopyObject()
+	gvk, err := ObjectKind(obj)
+	if err != nil {
+		return nil, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %T: %v", obj, err)
+	}
+	delete(m, "status")
+	removeCreationTimestamp(m)
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+// removeCreationTimestamp will remove the creationTimestamp from
+// every metadata, including the metadata of templates.
+func removeCreationTimestamp(obj interface{}) {
+	switch x := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range x {
+			if metadata, ok := value.(map[string]interface{}); ok && key == "metadata" {
+				delete(metadata, "creationTimestamp")
+			}
+			removeCreationTimestamp(value)
+		}
+	case []interface{}:
+		for _, item := range x {
+			removeCreationTimestamp(item)
+		}
 	}
-	fmt.Println(string(raw))
-	return nil
 }
@@ -0,0 +1,169 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestKubeYAML(t *testing.T) {
+	deployment := BusyboxDeployment("example")
+	deployment.Labels = map[string]string{"status": "stable"}
+	deployment.Annotations = map[string]string{"description": "the status of the app is below"}
+
+	raw, err := KubeYAML(deployment)
+	if err != nil {
+		t.Fatalf("unable to encode YAML: %v", err)
+	}
+	yaml := string(raw)
+	for _, expected := range []string{
+		"apiVersion: apps/v1\n",
+		"kind: Deployment\n",
+		"status: stable\n",
+		"description: the status of the app is below\n",
+		"image: busybox\n",
+	} {
+		if !strings.Contains(yaml, expected) {
+			t.Errorf("missing %q in YAML:\n%s", expected, yaml)
+		}
+	}
+	for _, unexpected := range []string{
+		"creationTimestamp",
+		"\nstatus:",
+	} {
+		if strings.Contains(yaml, unexpected) {
+			t.Errorf("unexpected %q in YAML:\n%s", unexpected, yaml)
+		}
+	}
+
+	// The original object is not changed
+	if deployment.Kind != "" {
+		t.Errorf("unexpected kind set on original object: %s", deployment.Kind)
+	}
+
+	// The YAML can be decoded by Kubernetes
+	decoded, _, err := scheme.Codecs.UniversalDeserializer().Decode(raw, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to decode YAML: %v", err)
+	}
+	if _, ok := decoded.(*appsv1.Deployment); !ok {
+		t.Errorf("unexpected decoded type: %T", decoded)
+	}
+}
+
+func TestRender(t *testing.T) {
+	app := &testApp{
+		objects: []runtime.Object{
+			BusyboxDeployment("example"),
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+		},
+	}
+	for _, test := range []struct {
+		name     string
+		opts     *RenderOptions
+		expected []string
+	}{
+		{"yaml", nil, []string{"kind: Deployment\n", "\n---\napiVersion: v1\nkind: Service\n"}},
+		{"json", &RenderOptions{Encoding: OutputJSON}, []string{"[\n  {\n", `"kind": "Service"`}},
+		{"jsonl", &RenderOptions{Encoding: OutputJSONLines}, []string{"\n{\"apiVersion\":\"v1\",\"kind\":\"Service\""}},
+		{"list", &RenderOptions{List: true}, []string{"kind: List\n", "- apiVersion: apps/v1\n  kind: Deployment\n"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := Render(buf, app, test.opts)
+			if err != nil {
+				t.Fatalf("unable to render: %v", err)
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("missing %q in output:\n%s", expected, buf.String())
+				}
+			}
+		})
+	}
+
+	// Every JSON line is a single object
+	buf := &bytes.Buffer{}
+	err := Render(buf, app, &RenderOptions{Encoding: OutputJSONLines})
+	if err != nil {
+		t.Fatalf("unable to render: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		_, _, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(line), nil, nil)
+		if err != nil {
+			t.Errorf("unable to decode JSON line: %v", err)
+		}
+	}
+}
+
+func TestRunRenderApps(t *testing.T) {
+	registry["render-a"] = &testApp{objects: []runtime.Object{BusyboxDeployment("a")}}
+	registry["render-b"] = &testApp{objects: []runtime.Object{BusyboxDeployment("b")}}
+	defer delete(registry, "render-a")
+	defer delete(registry, "render-b")
+	names := []string{"render-a", "render-b"}
+
+	// Every app is in a single JSON array
+	buf := &bytes.Buffer{}
+	err := RunRenderApps(buf, names, &RenderOptions{Encoding: OutputJSON})
+	if err != nil {
+		t.Fatalf("unable to render: %v", err)
+	}
+	var items []map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &items)
+	if err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, buf.String())
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 objects, got %d", len(items))
+	}
+
+	// Every app is in a single List
+	buf = &bytes.Buffer{}
+	err = RunRenderApps(buf, names, &RenderOptions{Encoding: OutputJSON, List: true})
+	if err != nil {
+		t.Fatalf("unable to render: %v", err)
+	}
+	list := map[string]interface{}{}
+	err = json.Unmarshal(buf.Bytes(), &list)
+	if err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, buf.String())
+	}
+	if len(list["items"].([]interface{})) != 2 {
+		t.Errorf("expected 2 items in list:\n%s", buf.String())
+	}
+}
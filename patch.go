@@ -0,0 +1,274 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// patches are the --patch and --json-patch patches, which
+// are applied after the profile and before the transformers.
+var patches []Transformer
+
+// AddPatches will add patches that are applied to the objects
+// of every application, after the selected profile.
+func AddPatches(ps ...Transformer) {
+	patches = append(patches, ps...)
+}
+
+// SetPatches will replace the patches.
+func SetPatches(ps ...Transformer) {
+	patches = ps
+}
+
+// patchInit will add the --patch files and --json-patch
+// target=file values to the patches.
+func patchInit(files, jsonPatches []string) error {
+	for _, file := range files {
+		ps, err := LoadStrategicMergePatches(file)
+		if err != nil {
+			return err
+		}
+		AddPatches(ps...)
+	}
+	for _, value := range jsonPatches {
+		p, err := LoadJSONPatch(value)
+		if err != nil {
+			return err
+		}
+		AddPatches(p)
+	}
+	return nil
+}
+
+// LoadStrategicMergePatches will read a YAML file with one strategic
+// merge patch per document.
+func LoadStrategicMergePatches(path string) ([]Transformer, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read patch: %v", err)
+	}
+	var ps []Transformer
+	for _, document := range splitDocuments(raw) {
+		if strings.TrimSpace(string(document)) == "" {
+			continue
+		}
+		ps = append(ps, StrategicMergePatch(string(document)))
+	}
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("empty patch: %s", path)
+	}
+	return ps, nil
+}
+
+// LoadJSONPatch will read a JSON patch from a value such as
+// "Deployment/web=patch.json", with the target before the file.
+func LoadJSONPatch(value string) (Transformer, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid JSON patch %q: expected Kind/name=file.json", value)
+	}
+	target, err := ParsePatchTarget(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	raw, err := ioutil.ReadFile(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to read JSON patch: %v", err)
+	}
+	return JSONPatch(target, string(raw)), nil
+}
+
+// PatchTarget selects the objects a patch is applied to.
+//
+// Empty fields match every object.
+type PatchTarget struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ParsePatchTarget will parse a target such as "Deployment/web"
+// or "Deployment/production/web".
+func ParsePatchTarget(target string) (*PatchTarget, error) {
+	parts := strings.Split(target, "/")
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("invalid patch target %q: expected Kind/name or Kind/namespace/name", target)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return &PatchTarget{Kind: parts[0], Name: parts[1]}, nil
+	case 3:
+		return &PatchTarget{Kind: parts[0], Namespace: parts[1], Name: parts[2]}, nil
+	}
+	return nil, fmt.Errorf("invalid patch target %q: expected Kind/name or Kind/namespace/name", target)
+}
+
+// String will return the target as Kind/namespace/name
+func (t *PatchTarget) String() string {
+	var parts []string
+	for _, part := range []string{t.Kind, t.Namespace, t.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Matches will return true if the target selects the object.
+func (t *PatchTarget) Matches(obj runtime.Object) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	gvk, err := ObjectKind(obj)
+	if err != nil {
+		return false
+	}
+	return (t.Kind == "" || t.Kind == gvk.Kind) &&
+		(t.Namespace == "" || t.Namespace == accessor.GetNamespace()) &&
+		(t.Name == "" || t.Name == accessor.GetName())
+}
+
+// StrategicMergePatch will return a Transformer that applies a strategic
+// merge patch, like kubectl patch. The patch is YAML or JSON, and is
+// applied to the object with the same kind, name and namespace (if set).
+//
+//	naml.StrategicMergePatch(`
+//	apiVersion: apps/v1
+//	kind: Deployment
+//	metadata:
+//	  name: web
+//	spec:
+//	  replicas: 5
+//	`)
+//
+// Kinds that are not in the Scheme are patched with a JSON merge patch.
+func StrategicMergePatch(patch string) Transformer {
+	return transformerFunc(func(objs []runtime.Object) ([]runtime.Object, error) {
+		raw, err := yaml.YAMLToJSON([]byte(patch))
+		if err != nil {
+			return nil, fmt.Errorf("invalid strategic merge patch: %v", err)
+		}
+		partial := &metav1.PartialObjectMetadata{}
+		err = json.Unmarshal(raw, partial)
+		if err != nil {
+			return nil, fmt.Errorf("invalid strategic merge patch: %v", err)
+		}
+		target := &PatchTarget{
+			Kind:      partial.Kind,
+			Namespace: partial.Namespace,
+			Name:      partial.Name,
+		}
+		if target.Kind == "" || target.Name == "" {
+			return nil, fmt.Errorf("invalid strategic merge patch: kind and metadata.name are required")
+		}
+		return patchObjects(objs, target, func(original []byte, obj runtime.Object) ([]byte, error) {
+			if _, ok := obj.(*unstructured.Unstructured); ok {
+				return jsonpatch.MergePatch(original, raw)
+			}
+			if _, _, err := Scheme.ObjectKinds(obj); err != nil {
+				return jsonpatch.MergePatch(original, raw)
+			}
+			return strategicpatch.StrategicMergePatch(original, raw, obj)
+		})
+	})
+}
+
+// JSONPatch will return a Transformer that applies an RFC 6902 JSON patch
+// to every object the target selects. The patch is YAML or JSON.
+//
+//	naml.JSONPatch(&naml.PatchTarget{Kind: "Deployment", Name: "web"}, `[
+//	  {"op": "replace", "path": "/spec/replicas", "value": 5}
+//	]`)
+func JSONPatch(target *PatchTarget, patch string) Transformer {
+	return transformerFunc(func(objs []runtime.Object) ([]runtime.Object, error) {
+		raw, err := yaml.YAMLToJSON([]byte(patch))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON patch: %v", err)
+		}
+		decoded, err := jsonpatch.DecodePatch(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON patch: %v", err)
+		}
+		return patchObjects(objs, target, func(original []byte, obj runtime.Object) ([]byte, error) {
+			return decoded.Apply(original)
+		})
+	})
+}
+
+// patchObjects will apply a patch to every object the target selects.
+// It is an error if the target does not select any object.
+func patchObjects(objs []runtime.Object, target *PatchTarget, apply func(original []byte, obj runtime.Object) ([]byte, error)) ([]runtime.Object, error) {
+	matched := false
+	for i, obj := range objs {
+		if !target.Matches(obj) {
+			continue
+		}
+		matched = true
+		original, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode %s: %v", target, err)
+		}
+		patched, err := apply(original, obj)
+		if err != nil {
+			return nil, fmt.Errorf("unable to patch %s: %v", target, err)
+		}
+		obj, err = decodeLike(obj, patched)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode patched %s: %v", target, err)
+		}
+		objs[i] = obj
+	}
+	if !matched {
+		return nil, &unmatchedError{fmt.Sprintf("patch target %s does not match any object", target)}
+	}
+	return objs, nil
+}
+
+// decodeLike will decode JSON into a new object of the same type as obj.
+func decodeLike(obj runtime.Object, raw []byte) (runtime.Object, error) {
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		u := &unstructured.Unstructured{}
+		err := u.UnmarshalJSON(raw)
+		return u, err
+	}
+	decoded := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	err := json.Unmarshal(raw, decoded)
+	return decoded, err
+}
@@ -0,0 +1,154 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	patchFile := filepath.Join(dir, "patch.yaml")
+	err := ioutil.WriteFile(patchFile, []byte(`
+# Scale the web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: NodePort
+`), 0644)
+	if err != nil {
+		t.Fatalf("unable to write patch: %v", err)
+	}
+	jsonPatchFile := filepath.Join(dir, "patch.json")
+	err = ioutil.WriteFile(jsonPatchFile, []byte(`[{"op": "replace", "path": "/spec/template/spec/containers/0/image", "value": "busybox:1.34"}]`), 0644)
+	if err != nil {
+		t.Fatalf("unable to write JSON patch: %v", err)
+	}
+
+	err = patchInit([]string{patchFile}, []string{"Deployment/web=" + jsonPatchFile})
+	if err != nil {
+		t.Fatalf("unable to load patches: %v", err)
+	}
+	defer SetPatches()
+	if len(patches) != 3 {
+		t.Fatalf("expected 3 patches, got %d", len(patches))
+	}
+
+	app := &testApp{
+		objects: []runtime.Object{
+			BusyboxDeployment("web"),
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+		},
+	}
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	deployment := objs[0].(*appsv1.Deployment)
+	if *deployment.Spec.Replicas != 3 || deployment.Spec.Template.Spec.Containers[0].Image != "busybox:1.34" {
+		t.Errorf("unexpected deployment: %d %s", *deployment.Spec.Replicas, deployment.Spec.Template.Spec.Containers[0].Image)
+	}
+	if service := objs[1].(*corev1.Service); service.Spec.Type != corev1.ServiceTypeNodePort {
+		t.Errorf("unexpected service type: %s", service.Spec.Type)
+	}
+
+	for _, invalid := range []string{"Deployment/web", "Deployment=" + jsonPatchFile, "Deployment/web=" + filepath.Join(dir, "missing.json")} {
+		if _, err := LoadJSONPatch(invalid); err == nil {
+			t.Errorf("expected error for JSON patch %q", invalid)
+		}
+	}
+}
+
+func TestPatchTarget(t *testing.T) {
+	target, err := ParsePatchTarget("Deployment/production/web")
+	if err != nil {
+		t.Fatalf("unable to parse target: %v", err)
+	}
+	if target.Kind != "Deployment" || target.Namespace != "production" || target.Name != "web" {
+		t.Errorf("unexpected target: %+v", target)
+	}
+	for _, invalid := range []string{"Deployment", "Deployment/", "a/b/c/d"} {
+		if _, err := ParsePatchTarget(invalid); err == nil {
+			t.Errorf("expected error for target %q", invalid)
+		}
+	}
+
+	_, err = JSONPatch(&PatchTarget{Kind: "Deployment", Name: "missing"}, `[]`).Transform([]runtime.Object{BusyboxDeployment("web")})
+	if err == nil {
+		t.Errorf("expected error for patch target without objects")
+	}
+}
+
+func TestPatchApps(t *testing.T) {
+	web := &namedTestApp{testApp: testApp{objects: []runtime.Object{BusyboxDeployment("web")}}, name: "patch-web"}
+	db := &namedTestApp{testApp: testApp{objects: []runtime.Object{BusyboxDeployment("db")}}, name: "patch-db"}
+	registry[web.name] = web
+	registry[db.name] = db
+	defer delete(registry, web.name)
+	defer delete(registry, db.name)
+	names := []string{db.name, web.name}
+
+	// Only one of the apps has to match each patch
+	SetPatches(JSONPatch(&PatchTarget{Kind: "Deployment", Name: "web"}, `[{"op": "replace", "path": "/spec/replicas", "value": 5}]`))
+	defer SetPatches()
+	buf := &bytes.Buffer{}
+	err := RunRenderApps(buf, names, nil)
+	if err != nil {
+		t.Fatalf("unable to render apps: %v", err)
+	}
+	if !strings.Contains(buf.String(), "replicas: 5") || !strings.Contains(buf.String(), "name: db") {
+		t.Errorf("expected both apps with the patch of web:\n%s", buf.String())
+	}
+
+	// A single app must match every patch
+	err = RunRender(&bytes.Buffer{}, db.name, nil)
+	if err == nil {
+		t.Errorf("expected error for a single app without a match")
+	}
+
+	// At least one app must match every patch
+	AddPatches(JSONPatch(&PatchTarget{Kind: "Deployment", Name: "cache"}, `[{"op": "replace", "path": "/spec/replicas", "value": 5}]`))
+	err = RunRenderApps(&bytes.Buffer{}, names, nil)
+	if err == nil || !strings.Contains(err.Error(), "Deployment/cache") {
+		t.Errorf("expected error for a patch of no app, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// profiles are the profiles of each application by name
+var profiles = make(map[string]map[string][]Transformer)
+
+// profile is the --profile value
+var profile string
+
+// RegisterProfile will register a named profile for an application,
+// such as dev, staging or prod.
+//
+// A profile is a set of patches, or any other Transformer, that are
+// applied to the objects of the application when the profile is
+// selected with --profile.
+//
+//	naml.RegisterProfile(app, "prod",
+//		naml.StrategicMergePatch(prodReplicas),
+//		naml.JSONPatch(&naml.PatchTarget{Kind: "Deployment", Name: "web"}, prodResources),
+//	)
+func RegisterProfile(app Deployable, name string, patches ...Transformer) {
+	appName := app.Meta().Name
+	if profiles[appName] == nil {
+		profiles[appName] = make(map[string][]Transformer)
+	}
+	profiles[appName][name] = append(profiles[appName][name], patches...)
+}
+
+// Profiles will return the sorted names of the profiles of an application.
+func Profiles(app Deployable) []string {
+	var names []string
+	for name := range profiles[app.Meta().Name] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetProfile will select the profile used for every application.
+// An empty name will not use a profile.
+func SetProfile(name string) {
+	profile = name
+}
+
+// profileTransform will apply the selected profile of an application.
+func profileTransform(app Deployable, objs []runtime.Object) ([]runtime.Object, error) {
+	if profile == "" {
+		return objs, nil
+	}
+	patches, ok := profiles[app.Meta().Name][profile]
+	if !ok {
+		return nil, &unmatchedError{fmt.Sprintf("app %s does not have profile %q: expected one of [%s]", app.Meta().Name, profile, strings.Join(Profiles(app), ", "))}
+	}
+	var err error
+	for _, patch := range patches {
+		objs, err = patch.Transform(objs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply profile %s: %v", profile, err)
+		}
+	}
+	return objs, nil
+}
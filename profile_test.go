@@ -0,0 +1,161 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestProfiles(t *testing.T) {
+	deployment := BusyboxDeployment("web")
+	deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar", Image: "sidecar"})
+	custom := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"size": int64(1), "mode": "debug"},
+	}}
+	custom.SetAPIVersion("naml.dev/v1")
+	custom.SetKind("Example")
+	custom.SetName("web")
+	app := &testApp{
+		objects: []runtime.Object{
+			deployment,
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+			custom,
+		},
+	}
+	RegisterProfile(app, "prod",
+		StrategicMergePatch(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: web
+        env:
+        - name: ENVIRONMENT
+          value: prod
+`),
+		JSONPatch(&PatchTarget{Kind: "Service", Name: "web"}, `[{"op": "add", "path": "/metadata/labels", "value": {"env": "prod"}}]`),
+		StrategicMergePatch(`{"apiVersion": "naml.dev/v1", "kind": "Example", "metadata": {"name": "web"}, "spec": {"size": 3, "mode": null}}`),
+	)
+	RegisterProfile(app, "dev")
+	defer delete(profiles, app.Meta().Name)
+
+	if names := Profiles(app); strings.Join(names, ",") != "dev,prod" {
+		t.Errorf("unexpected profiles: %v", names)
+	}
+
+	SetProfile("prod")
+	defer SetProfile("")
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	patched := objs[0].(*appsv1.Deployment)
+	if *patched.Spec.Replicas != 5 {
+		t.Errorf("expected 5 replicas, got %d", *patched.Spec.Replicas)
+	}
+	containers := patched.Spec.Template.Spec.Containers
+	if len(containers) != 2 || containers[0].Image != "busybox" || len(containers[0].Env) != 1 || containers[1].Name != "sidecar" {
+		t.Errorf("expected containers to be merged by name: %+v", containers)
+	}
+	if labels := objs[1].(*corev1.Service).Labels; labels["env"] != "prod" {
+		t.Errorf("expected service label from JSON patch: %v", labels)
+	}
+	spec := objs[2].(*unstructured.Unstructured).Object["spec"].(map[string]interface{})
+	if spec["size"] != int64(3) && spec["size"] != float64(3) {
+		t.Errorf("expected size 3, got %v", spec["size"])
+	}
+	if _, ok := spec["mode"]; ok {
+		t.Errorf("expected mode to be removed by merge patch")
+	}
+
+	// The objects of the application are not changed
+	if *deployment.Spec.Replicas != 1 {
+		t.Errorf("expected original replicas to be unchanged")
+	}
+
+	SetProfile("staging")
+	_, err = TransformedObjects(app)
+	if err == nil || !strings.Contains(err.Error(), "dev, prod") {
+		t.Errorf("expected error listing profiles, got %v", err)
+	}
+}
+
+type namedTestApp struct {
+	testApp
+	name string
+}
+
+func (n *namedTestApp) Meta() *AppMeta {
+	return &AppMeta{ObjectMeta: metav1.ObjectMeta{Name: n.name}}
+}
+
+func TestProfileApps(t *testing.T) {
+	web := &namedTestApp{testApp: testApp{objects: []runtime.Object{BusyboxDeployment("web")}}, name: "profile-web"}
+	db := &namedTestApp{testApp: testApp{objects: []runtime.Object{BusyboxDeployment("db")}}, name: "profile-db"}
+	registry[web.name] = web
+	registry[db.name] = db
+	defer delete(registry, web.name)
+	defer delete(registry, db.name)
+	RegisterProfile(web, "prod", JSONPatch(&PatchTarget{Kind: "Deployment", Name: "web"}, `[{"op": "replace", "path": "/spec/replicas", "value": 5}]`))
+	defer delete(profiles, web.name)
+	names := []string{db.name, web.name}
+
+	// Only one of the apps has to have the profile
+	SetProfile("prod")
+	defer SetProfile("")
+	buf := &bytes.Buffer{}
+	err := RunRenderApps(buf, names, nil)
+	if err != nil {
+		t.Fatalf("unable to render apps: %v", err)
+	}
+	if !strings.Contains(buf.String(), "replicas: 5") || !strings.Contains(buf.String(), "name: db") {
+		t.Errorf("expected both apps with the profile of web:\n%s", buf.String())
+	}
+
+	// A single app must have the profile
+	err = RunRender(&bytes.Buffer{}, db.name, nil)
+	if err == nil {
+		t.Errorf("expected error for a single app without the profile")
+	}
+
+	// At least one app must have the profile
+	SetProfile("staging")
+	err = RunRenderApps(&bytes.Buffer{}, names, nil)
+	if err == nil || !strings.Contains(err.Error(), "no app has profile") {
+		t.Errorf("expected error for a profile of no app, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RBACNamePrefix is the prefix of the default name of the
+// ServiceAccount, roles and bindings for an application
+const RBACNamePrefix = "naml-"
+
+// rbacNameUnsafe are the characters that are not allowed in the
+// default name of the ServiceAccount
+var rbacNameUnsafe = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// RBACServiceAccountName will return the default name of the
+// ServiceAccount that installs an application.
+func RBACServiceAccountName(app Deployable) string {
+	name := rbacNameUnsafe.ReplaceAllString(strings.ToLower(app.Meta().Name), "-")
+	return RBACNamePrefix + strings.Trim(name, "-")
+}
+
+// rbacResource is a resource of an application and the names
+// of its objects
+type rbacResource struct {
+	group    string
+	resource string
+	names    map[string]bool
+}
+
+// rbacRules is a set of resources by group and resource
+type rbacRules map[string]*rbacResource
+
+// add will add an object to the rules.
+func (r rbacRules) add(group, resource, name string) {
+	key := group + "/" + resource
+	if r[key] == nil {
+		r[key] = &rbacResource{
+			group:    group,
+			resource: resource,
+			names:    make(map[string]bool),
+		}
+	}
+	r[key].names[name] = true
+}
+
+// policyRules will return the minimal rules to create every resource,
+// and to get and delete the objects by name, followed by held.
+//
+// Kubernetes only lets the ServiceAccount create a Role or ClusterRole
+// with permissions it already has, so held are the rules of the roles of
+// the application. Only the roles of the application can be bound, and
+// escalate is never allowed, so the ServiceAccount can not give itself
+// more than the application needs.
+func (r rbacRules) policyRules(held []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var keys []string
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var rules []rbacv1.PolicyRule
+	for _, key := range keys {
+		res := r[key]
+		var names []string
+		for name := range res.names {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{res.group},
+			Resources: []string{res.resource},
+			Verbs:     []string{"create"},
+		}, rbacv1.PolicyRule{
+			APIGroups:     []string{res.group},
+			Resources:     []string{res.resource},
+			ResourceNames: names,
+			Verbs:         []string{"get", "delete"},
+		})
+		if res.group == rbacv1.GroupName && (res.resource == "roles" || res.resource == "clusterroles") {
+			rules = append(rules, rbacv1.PolicyRule{
+				APIGroups:     []string{res.group},
+				Resources:     []string{res.resource},
+				ResourceNames: names,
+				Verbs:         []string{"bind"},
+			})
+		}
+	}
+	for _, rule := range held {
+		if !containsRule(rules, rule) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// containsRule will return true if rules has a rule equal to rule.
+func containsRule(rules []rbacv1.PolicyRule, rule rbacv1.PolicyRule) bool {
+	for _, r := range rules {
+		if reflect.DeepEqual(r, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// roleRules will return the rules of a Role or ClusterRole.
+func roleRules(obj runtime.Object) []rbacv1.PolicyRule {
+	switch x := obj.(type) {
+	case *rbacv1.Role:
+		return x.Rules
+	case *rbacv1.ClusterRole:
+		return x.Rules
+	}
+	return nil
+}
+
+// RBAC will return a ServiceAccount with the minimal roles and bindings
+// that are needed to install and uninstall an application, for example
+// from a Job in Kubernetes.
+//
+// Objects in namespaces that already exist are allowed with a Role in the
+// namespace. Cluster scoped objects, and objects in the namespaces that
+// the application creates, are allowed with a ClusterRole.
+//
+// The application must already be installed, either in Kubernetes
+// or "nowhere" with app.Install(nil).
+func RBAC(app Deployable, serviceAccount, namespace string) ([]runtime.Object, error) {
+	if serviceAccount == "" {
+		serviceAccount = RBACServiceAccountName(app)
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make(map[string]bool)
+	for _, obj := range objs {
+		if ns, ok := obj.(*corev1.Namespace); ok {
+			created[ns.Name] = true
+		}
+	}
+	crds := crdScopes(objs)
+	clusterRules := make(rbacRules)
+	namespaceRules := make(map[string]rbacRules)
+	var clusterHeld []rbacv1.PolicyRule
+	namespaceHeld := make(map[string][]rbacv1.PolicyRule)
+	for _, obj := range objs {
+		gvk, err := ObjectKind(obj)
+		if err != nil {
+			return nil, err
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find metadata for %T: %v", obj, err)
+		}
+		resource, _ := meta.UnsafeGuessKindToResource(gvk)
+		ns := accessor.GetNamespace()
+		if clusterScoped(obj, crds) || created[ns] {
+			clusterRules.add(gvk.Group, resource.Resource, accessor.GetName())
+			clusterHeld = append(clusterHeld, roleRules(obj)...)
+			continue
+		}
+		if ns == "" {
+			ns = metav1.NamespaceDefault
+		}
+		if namespaceRules[ns] == nil {
+			namespaceRules[ns] = make(rbacRules)
+		}
+		namespaceRules[ns].add(gvk.Group, resource.Resource, accessor.GetName())
+		namespaceHeld[ns] = append(namespaceHeld[ns], roleRules(obj)...)
+	}
+
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      serviceAccount,
+		Namespace: namespace,
+	}}
+	rbac := []runtime.Object{
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      serviceAccount,
+				Namespace: namespace,
+			},
+		},
+	}
+	if len(clusterRules) > 0 {
+		rbac = append(rbac, &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: serviceAccount,
+			},
+			Rules: clusterRules.policyRules(clusterHeld),
+		}, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: serviceAccount,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     serviceAccount,
+			},
+			Subjects: subjects,
+		})
+	}
+	var namespaces []string
+	for ns := range namespaceRules {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		rbac = append(rbac, &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      serviceAccount,
+				Namespace: ns,
+			},
+			Rules: namespaceRules[ns].policyRules(namespaceHeld[ns]),
+		}, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      serviceAccount,
+				Namespace: ns,
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     serviceAccount,
+			},
+			Subjects: subjects,
+		})
+	}
+	err = SetObjectKinds(rbac)
+	if err != nil {
+		return nil, err
+	}
+	return rbac, nil
+}
+
+// RunRBAC will find an application by name, install it "nowhere"
+// and render its RBAC to w.
+func RunRBAC(w io.Writer, appName, serviceAccount, namespace string, o OutputEncoding) error {
+	app := Find(appName)
+	if app == nil {
+		return fmt.Errorf("unable to find app: %s", appName)
+	}
+
+	err := Configure(app)
+	if err != nil {
+		return err
+	}
+
+	// Install the application "nowhere" to register the components in memory
+	err = app.Install(nil)
+	if err != nil {
+		return fmt.Errorf("unable to install app in memory: %v", err)
+	}
+	objs, err := RBAC(app, serviceAccount, namespace)
+	if err != nil {
+		return err
+	}
+	return RenderObjects(w, objs, &RenderOptions{Encoding: o})
+}
@@ -0,0 +1,174 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRBAC(t *testing.T) {
+	deployment := BusyboxDeployment("api")
+	deployment.Namespace = "apps"
+	app := transformApp()
+	app.objects = append(app.objects,
+		deployment,
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "api-internal", Namespace: "apps"}},
+	)
+
+	objs, err := RBAC(app, "", "ci")
+	if err != nil {
+		t.Fatalf("unable to generate rbac: %v", err)
+	}
+	if len(objs) != 5 {
+		t.Fatalf("expected a ServiceAccount, a ClusterRole, a Role and bindings, got %d objects", len(objs))
+	}
+	sa := objs[0].(*corev1.ServiceAccount)
+	if sa.Name != "naml-test-app" || sa.Namespace != "ci" {
+		t.Errorf("unexpected ServiceAccount %s/%s", sa.Namespace, sa.Name)
+	}
+	if kind := sa.GetObjectKind().GroupVersionKind().Kind; kind != "ServiceAccount" {
+		t.Errorf("expected kinds to be set, got %q", kind)
+	}
+
+	// The web namespace is created by the app, so its objects are in the ClusterRole
+	clusterRole := objs[1].(*rbacv1.ClusterRole)
+	if !allows(clusterRole.Rules, "", "namespaces", "web", "delete") {
+		t.Errorf("expected the ClusterRole to delete the web namespace")
+	}
+	if !allows(clusterRole.Rules, "apps", "deployments", "", "create") {
+		t.Errorf("expected the ClusterRole to create deployments")
+	}
+	if !allows(clusterRole.Rules, "rbac.authorization.k8s.io", "clusterroles", "web", "bind") {
+		t.Errorf("expected the ClusterRole to bind the web ClusterRole")
+	}
+	if allows(clusterRole.Rules, "", "services", "", "create") {
+		t.Errorf("expected services in an existing namespace to be in a Role")
+	}
+	binding := objs[2].(*rbacv1.ClusterRoleBinding)
+	if binding.RoleRef.Name != clusterRole.Name || binding.Subjects[0].Namespace != "ci" {
+		t.Errorf("unexpected ClusterRoleBinding: %v %v", binding.RoleRef, binding.Subjects)
+	}
+
+	role := objs[3].(*rbacv1.Role)
+	if role.Namespace != "apps" {
+		t.Errorf("expected a Role in apps, got %s", role.Namespace)
+	}
+	if !allows(role.Rules, "", "services", "api-internal", "get") {
+		t.Errorf("expected the Role to get the api-internal service")
+	}
+	if allows(role.Rules, "", "services", "other", "delete") {
+		t.Errorf("expected the Role to only delete the services of the app")
+	}
+	if objs[4].(*rbacv1.RoleBinding).Namespace != "apps" {
+		t.Errorf("expected a RoleBinding in apps")
+	}
+}
+
+func TestRBACSelfElevation(t *testing.T) {
+	app := transformApp()
+	app.objects[3].(*rbacv1.ClusterRole).Rules = []rbacv1.PolicyRule{{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"get", "list"},
+	}}
+	app.objects = append(app.objects, &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"watch"},
+		}},
+	})
+	objs, err := RBAC(app, "", "ci")
+	if err != nil {
+		t.Fatalf("unable to generate rbac: %v", err)
+	}
+	clusterRole := objs[1].(*rbacv1.ClusterRole)
+	role := objs[3].(*rbacv1.Role)
+
+	// The rules of the roles of the app are held, so the roles can be created
+	if !allows(clusterRole.Rules, "", "pods", "", "list") {
+		t.Errorf("expected the ClusterRole to hold the rules of the web ClusterRole")
+	}
+	if !allows(role.Rules, "", "configmaps", "", "watch") {
+		t.Errorf("expected the Role to hold the rules of the api Role")
+	}
+
+	// Only the roles of the app can be bound, and nothing can be escalated
+	roleNames := map[string]bool{"web": true, "api": true}
+	for _, rules := range [][]rbacv1.PolicyRule{clusterRole.Rules, role.Rules} {
+		for _, rule := range rules {
+			for _, verb := range rule.Verbs {
+				switch verb {
+				case "escalate", "*":
+					t.Errorf("unexpected %s in rule %v", verb, rule)
+				case "bind":
+					if len(rule.ResourceNames) == 0 {
+						t.Errorf("expected bind to be limited to the roles of the app: %v", rule)
+					}
+					for _, name := range rule.ResourceNames {
+						if !roleNames[name] {
+							t.Errorf("unexpected bind of role %s", name)
+						}
+					}
+				}
+			}
+			for _, resource := range rule.Resources {
+				if resource == "*" {
+					t.Errorf("unexpected wildcard resource in rule %v", rule)
+				}
+			}
+		}
+	}
+	if allows(clusterRole.Rules, "rbac.authorization.k8s.io", "clusterroles", "cluster-admin", "bind") {
+		t.Errorf("expected the ServiceAccount to not bind cluster-admin")
+	}
+}
+
+// allows will return true if a rule allows the verb on a resource and name.
+// An empty name is any name.
+func allows(rules []rbacv1.PolicyRule, group, resource, name, verb string) bool {
+	contains := func(list []string, s string) bool {
+		for _, item := range list {
+			if item == s {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rule := range rules {
+		if !contains(rule.APIGroups, group) || !contains(rule.Resources, resource) || !contains(rule.Verbs, verb) {
+			continue
+		}
+		if len(rule.ResourceNames) == 0 || (name != "" && contains(rule.ResourceNames, name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -47,6 +47,7 @@ func Register(app Deployable) {
 	RegisterAndExit(app)
 }
 
+// RegisterAndError will register the app or return an error
 func RegisterAndError(app Deployable) error {
 
 	// Validate the application
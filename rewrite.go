@@ -0,0 +1,245 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// ImageRewriteFileEnvironmentalVariable can be used to set the file
+	// that image rewrite rules are read from.
+	ImageRewriteFileEnvironmentalVariable = "NAML_IMAGE_REWRITE_FILE"
+
+	// ImageRewriteSeparator separates the image to match from
+	// its replacement in an image rewrite rule.
+	ImageRewriteSeparator = "=>"
+
+	// DockerHubRegistry is the registry of images without a registry.
+	DockerHubRegistry = "docker.io"
+)
+
+// imageRewrites are the rules applied to every image at install
+// and output time.
+var imageRewrites []*ImageRewrite
+
+// ImageRewrite is a rule that rewrites a container image, such as a
+// mirror of a public registry.
+//
+//	docker.io/* => registry.corp/dockerhub/*
+//	quay.io/prometheus/node-exporter => registry.corp/node-exporter
+//
+// Images are normalized before they are matched, so "nginx:1.21" is
+// matched as "docker.io/library/nginx:1.21".
+//
+// A rule ending with "*" matches every image with the prefix, and the
+// rest of the image is appended to the replacement. Any other rule
+// matches a single image, and keeps the tag or digest of the image
+// unless the rule has its own.
+type ImageRewrite struct {
+	From string
+	To   string
+}
+
+// ParseImageRewrite will parse a rule such as
+// "docker.io/* => registry.corp/dockerhub/*".
+func ParseImageRewrite(rule string) (*ImageRewrite, error) {
+	parts := strings.Split(rule, ImageRewriteSeparator)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid image rewrite %q: expected <image> %s <image>", rule, ImageRewriteSeparator)
+	}
+	r := &ImageRewrite{
+		From: strings.TrimSpace(parts[0]),
+		To:   strings.TrimSpace(parts[1]),
+	}
+	if r.From == "" || r.To == "" {
+		return nil, fmt.Errorf("invalid image rewrite %q: empty image", rule)
+	}
+	for _, image := range []string{r.From, r.To} {
+		if strings.Contains(strings.TrimSuffix(image, "*"), "*") {
+			return nil, fmt.Errorf("invalid image rewrite %q: * is only supported at the end", rule)
+		}
+	}
+	if strings.HasSuffix(r.From, "*") != strings.HasSuffix(r.To, "*") {
+		return nil, fmt.Errorf("invalid image rewrite %q: both images must end with *, or neither", rule)
+	}
+	return r, nil
+}
+
+// ReadImageRewrites will read one rule per line. Empty lines
+// and lines starting with # are ignored.
+func ReadImageRewrites(r io.Reader) ([]*ImageRewrite, error) {
+	var rules []*ImageRewrite
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseImageRewrite(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// LoadImageRewrites will read the rules in a file with ReadImageRewrites.
+func LoadImageRewrites(path string) ([]*ImageRewrite, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open image rewrites: %v", err)
+	}
+	defer f.Close()
+	rules, err := ReadImageRewrites(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image rewrites %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// SetImageRewrites will set the rules that are applied to every
+// image at install and output time. The first rule that matches
+// an image is used.
+func SetImageRewrites(rules ...*ImageRewrite) {
+	imageRewrites = rules
+}
+
+// imageRewriteInit will set the rules from the --image-rewrite flags,
+// and the --image-rewrite-file or $NAML_IMAGE_REWRITE_FILE file.
+func imageRewriteInit(flags []string, file string) error {
+	var rules []*ImageRewrite
+	for _, flag := range flags {
+		rule, err := ParseImageRewrite(flag)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if file == "" {
+		file = os.Getenv(ImageRewriteFileEnvironmentalVariable)
+	}
+	if file != "" {
+		fileRules, err := LoadImageRewrites(file)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+	}
+	SetImageRewrites(rules...)
+	return nil
+}
+
+// Rewrite will return the rewritten image, and true if the rule matches.
+func (r *ImageRewrite) Rewrite(image string) (string, bool) {
+	normalized := NormalizeImage(image)
+	if strings.HasSuffix(r.From, "*") {
+		prefix := strings.TrimSuffix(r.From, "*")
+		if !strings.HasPrefix(normalized, prefix) {
+			return image, false
+		}
+		return strings.TrimSuffix(r.To, "*") + strings.TrimPrefix(normalized, prefix), true
+	}
+	from := NormalizeImage(r.From)
+	if normalized == from {
+		return r.To, true
+	}
+	repository, suffix := splitImage(normalized)
+	if repository != from {
+		return image, false
+	}
+	if _, toSuffix := splitImage(r.To); toSuffix != "" {
+		return r.To, true
+	}
+	return r.To + suffix, true
+}
+
+// RewriteImage will rewrite an image with the first rule that matches.
+func RewriteImage(image string, rules []*ImageRewrite) string {
+	for _, rule := range rules {
+		if rewritten, ok := rule.Rewrite(image); ok {
+			return rewritten
+		}
+	}
+	return image
+}
+
+// RewriteImages will rewrite every image of a workload in place.
+func RewriteImages(obj runtime.Object, rules []*ImageRewrite) {
+	if len(rules) == 0 {
+		return
+	}
+	WalkImages(obj, func(container string, image *string, path *field.Path) {
+		*image = RewriteImage(*image, rules)
+	})
+}
+
+// NormalizeImage will return the fully qualified name of an image.
+//
+//	nginx                  docker.io/library/nginx
+//	kubernetesui/dashboard docker.io/kubernetesui/dashboard
+//	quay.io/cilium/cilium  quay.io/cilium/cilium
+func NormalizeImage(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 || (!strings.ContainsAny(image[:i], ".:") && image[:i] != "localhost") {
+		image = DockerHubRegistry + "/" + image
+	}
+	image = strings.Replace(image, "index.docker.io/", DockerHubRegistry+"/", 1)
+	if strings.HasPrefix(image, DockerHubRegistry+"/") {
+		repository, _ := splitImage(strings.TrimPrefix(image, DockerHubRegistry+"/"))
+		if !strings.Contains(repository, "/") {
+			image = DockerHubRegistry + "/library/" + strings.TrimPrefix(image, DockerHubRegistry+"/")
+		}
+	}
+	return image
+}
+
+// splitImage will split an image into its repository and
+// its tag and digest, such as ":1.21" or "@sha256:abc".
+func splitImage(image string) (string, string) {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name, image[len(name):]
+}
+
+// Transform will rewrite every image of a workload in place.
+func (r *ImageRewrite) Transform(objs []runtime.Object) ([]runtime.Object, error) {
+	for _, obj := range objs {
+		RewriteImages(obj, []*ImageRewrite{r})
+	}
+	return objs, nil
+}
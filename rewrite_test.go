@@ -0,0 +1,104 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRewriteImage(t *testing.T) {
+	rules, err := ReadImageRewrites(strings.NewReader(`
+# Mirror of Docker Hub
+quay.io/prometheus/node-exporter => registry.corp/node-exporter
+nginx:1.20 => registry.corp/nginx:1.20-patched
+docker.io/* => registry.corp/dockerhub/*
+`))
+	if err != nil {
+		t.Fatalf("unable to read rules: %v", err)
+	}
+	for image, expected := range map[string]string{
+		"nginx":                                   "registry.corp/dockerhub/library/nginx",
+		"nginx:1.21":                              "registry.corp/dockerhub/library/nginx:1.21",
+		"nginx:1.20":                              "registry.corp/nginx:1.20-patched",
+		"docker.io/nginx@sha256:abc":              "registry.corp/dockerhub/library/nginx@sha256:abc",
+		"kubernetesui/dashboard:v2.0.0":           "registry.corp/dockerhub/kubernetesui/dashboard:v2.0.0",
+		"index.docker.io/kubernetesui/dashboard":  "registry.corp/dockerhub/kubernetesui/dashboard",
+		"quay.io/prometheus/node-exporter:v1.2.0": "registry.corp/node-exporter:v1.2.0",
+		"quay.io/cilium/cilium:v1.10.0":           "quay.io/cilium/cilium:v1.10.0",
+		"localhost:5000/nginx":                    "localhost:5000/nginx",
+	} {
+		if actual := RewriteImage(image, rules); actual != expected {
+			t.Errorf("expected %s => %s, got %s", image, expected, actual)
+		}
+	}
+}
+
+func TestParseImageRewriteInvalid(t *testing.T) {
+	for _, rule := range []string{
+		"docker.io/*",
+		"docker.io/* => ",
+		"docker.io/* => registry.corp/dockerhub",
+		"docker.io/*/nginx => registry.corp/nginx",
+	} {
+		_, err := ParseImageRewrite(rule)
+		if err == nil {
+			t.Errorf("expected error for rule %q", rule)
+		}
+	}
+}
+
+func TestTransformedObjectsRewriteImages(t *testing.T) {
+	rule, err := ParseImageRewrite("docker.io/* => registry.corp/dockerhub/*")
+	if err != nil {
+		t.Fatalf("unable to parse rule: %v", err)
+	}
+	SetImageRewrites(rule)
+	defer SetImageRewrites()
+
+	cronjob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup"}}
+	cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "backup", Image: "backup:1.0"}}
+	app := &testApp{
+		objects: []runtime.Object{BusyboxDeployment("example"), cronjob},
+	}
+	images, err := Images(app)
+	if err != nil {
+		t.Fatalf("unable to find images: %v", err)
+	}
+	for _, image := range images {
+		if !strings.HasPrefix(image.Image, "registry.corp/dockerhub/library/") {
+			t.Errorf("expected %s to be rewritten", image.Image)
+		}
+	}
+
+	// The objects of the application are not changed
+	if image := cronjob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image; image != "backup:1.0" {
+		t.Errorf("expected original image to be unchanged, got %s", image)
+	}
+}
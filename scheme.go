@@ -0,0 +1,115 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kris-nova/naml/codify"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// Scheme is used to find the apiVersion and kind of every object.
+//
+// It knows every kind in the client-go scheme and CustomResourceDefinitions.
+// Use AddToScheme to add custom kinds.
+var Scheme = runtime.NewScheme()
+
+var schemeMtx sync.RWMutex
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(Scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(Scheme))
+}
+
+// AddToScheme will add custom kinds to the Scheme.
+//
+//	naml.AddToScheme(examplev1.AddToScheme)
+func AddToScheme(fns ...func(*runtime.Scheme) error) error {
+	schemeMtx.Lock()
+	defer schemeMtx.Unlock()
+	for _, fn := range fns {
+		err := fn(Scheme)
+		if err != nil {
+			return fmt.Errorf("unable to add to scheme: %v", err)
+		}
+	}
+	return nil
+}
+
+// ObjectKind will return the GroupVersionKind of an object from the Scheme,
+// or from the scheme of a registered codifier.
+//
+// Objects that are not in either scheme must already have their apiVersion and kind set.
+func ObjectKind(obj runtime.Object) (schema.GroupVersionKind, error) {
+	schemeMtx.RLock()
+	gvks, _, err := Scheme.ObjectKinds(obj)
+	schemeMtx.RUnlock()
+	if err == nil && len(gvks) > 0 {
+		return gvks[0], nil
+	}
+	gvks, err = codify.ObjectKinds(obj)
+	if err == nil && len(gvks) > 0 {
+		return gvks[0], nil
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return gvk, fmt.Errorf("unable to find kind for %T: not registered in the scheme", obj)
+	}
+	return gvk, nil
+}
+
+// SetObjectKinds will set the apiVersion and kind of every object in place.
+func SetObjectKinds(objs []runtime.Object) error {
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+		gvk, err := ObjectKind(obj)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	return nil
+}
+
+// AppObjects will return the objects of an application with their
+// apiVersion and kind set.
+//
+// The application must already be installed, either in Kubernetes
+// or "nowhere" with app.Install(nil).
+func AppObjects(app Deployable) ([]runtime.Object, error) {
+	objs := app.Objects()
+	err := SetObjectKinds(objs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to set kinds for app %s: %v", app.Meta().Name, err)
+	}
+	return objs, nil
+}
@@ -0,0 +1,91 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// testApp is a Deployable that only has objects
+type testApp struct {
+	objects []runtime.Object
+}
+
+func (t *testApp) Install(client kubernetes.Interface) error   { return nil }
+func (t *testApp) Uninstall(client kubernetes.Interface) error { return nil }
+func (t *testApp) Objects() []runtime.Object                   { return t.objects }
+func (t *testApp) Meta() *AppMeta {
+	return &AppMeta{ObjectMeta: metav1.ObjectMeta{Name: "test-app"}}
+}
+
+func TestAppObjects(t *testing.T) {
+	app := &testApp{
+		objects: []runtime.Object{
+			BusyboxDeployment("example"),
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "example"}},
+		},
+	}
+	objs, err := AppObjects(app)
+	if err != nil {
+		t.Fatalf("unable to set kinds: %v", err)
+	}
+	expected := []string{"apps/v1 Deployment", "v1 Service"}
+	for i, obj := range objs {
+		apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+		if actual := apiVersion + " " + kind; actual != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], actual)
+		}
+	}
+
+	// The kinds are set on the objects of the application
+	raw, err := json.Marshal(app.Objects())
+	if err != nil {
+		t.Fatalf("unable to marshal JSON: %v", err)
+	}
+	if !strings.Contains(string(raw), `"kind":"Service","apiVersion":"v1"`) {
+		t.Errorf("missing kind in JSON: %s", raw)
+	}
+}
+
+func TestAppObjectsUnknownKind(t *testing.T) {
+	app := &testApp{
+		objects: []runtime.Object{&metav1.Status{}},
+	}
+	_, err := AppObjects(app)
+	if err != nil {
+		t.Fatalf("unexpected error for kind in the scheme: %v", err)
+	}
+	app.objects = []runtime.Object{&runtime.Unknown{}}
+	_, err = AppObjects(app)
+	if err == nil {
+		t.Errorf("expected error for kind without apiVersion and kind")
+	}
+}
@@ -0,0 +1,223 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// SecretsDirectoryEnvironmentalVariable can be used to set the directory
+	// that Secret values are looked up from at install time.
+	SecretsDirectoryEnvironmentalVariable = "NAML_SECRETS_DIR"
+
+	// SecretEnvironmentalVariablePrefix is the prefix for every environmental
+	// variable that Secret values are looked up from at install time.
+	SecretEnvironmentalVariablePrefix = "NAML_SECRET_"
+)
+
+// secretValues are Secret values that have been set in Go.
+var secretValues = make(map[string][]byte)
+
+// secretsDirectory is the --secrets-dir value which
+// takes precedence over the environmental variable.
+var secretsDirectory string
+
+// SetSecretValues can be used to provide Secret values in Go.
+//
+// The map is keyed by SecretValueKey(), such as "default/my-secret/password".
+// Values set here take precedence over environmental variables and files.
+func SetSecretValues(values map[string][]byte) {
+	for key, value := range values {
+		secretValues[key] = value
+	}
+}
+
+// SecretValueKey is the key used in SetSecretValues() for a single Secret key.
+//
+// This is also the path used to look up a value in the secrets directory.
+// A Secret without a namespace uses the "default" namespace.
+func SecretValueKey(namespace, name, key string) string {
+	return path.Join(secretNamespace(namespace), name, key)
+}
+
+// SecretEnvironmentalVariable is the name of the environmental variable
+// used to look up a single Secret key at install time.
+//
+//	default, my-secret, password    NAML_SECRET_DEFAULT_MY_SECRET_PASSWORD
+//
+// A Secret without a namespace uses the "default" namespace. Every character
+// that is not a letter or a number is replaced with "_", so more than one key
+// can have the same variable. CheckSecretLookups will find those keys.
+func SecretEnvironmentalVariable(namespace, name, key string) string {
+	reg := regexp.MustCompile("[^A-Z0-9]+")
+	var parts []string
+	for _, part := range []string{secretNamespace(namespace), name, key} {
+		if part == "" {
+			continue
+		}
+		parts = append(parts, reg.ReplaceAllString(strings.ToUpper(part), "_"))
+	}
+	return SecretEnvironmentalVariablePrefix + strings.Join(parts, "_")
+}
+
+// secretNamespace will return the namespace used to look up Secret values.
+func secretNamespace(namespace string) string {
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
+
+// CheckSecretLookups will return an error if two keys in the lookups are
+// looked up from the same environmental variable.
+//
+// The lookups are the keys to look up for each Secret, by SecretValueKey(namespace, name, "").
+func CheckSecretLookups(lookups map[string][]string) error {
+	var secrets []string
+	for secret := range lookups {
+		secrets = append(secrets, secret)
+	}
+	sort.Strings(secrets)
+	variables := make(map[string]string)
+	var collisions []string
+	for _, secret := range secrets {
+		parts := strings.SplitN(secret, "/", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid Secret %s, expected namespace/name", secret)
+		}
+		for _, key := range lookups[secret] {
+			valueKey := SecretValueKey(parts[0], parts[1], key)
+			envVar := SecretEnvironmentalVariable(parts[0], parts[1], key)
+			if other, ok := variables[envVar]; ok && other != valueKey {
+				collisions = append(collisions, fmt.Sprintf("  %s and %s are both looked up from $%s", other, valueKey, envVar))
+				continue
+			}
+			variables[envVar] = valueKey
+		}
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("unable to look up Secret values:\n%s", strings.Join(collisions, "\n"))
+	}
+	return nil
+}
+
+// LookupSecretData will look up the values for keys in a Secret at install time,
+// and set them in the Secret Data.
+//
+// Each value is looked up from the first of the following:
+//
+//  1. Values set with SetSecretValues()
+//  2. The environmental variable from SecretEnvironmentalVariable()
+//  3. The file SecretValueKey() in the --secrets-dir or $NAML_SECRETS_DIR directory
+//
+// An error is returned listing every key that is missing a value.
+func LookupSecretData(secret *corev1.Secret, keys ...string) error {
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	directory := secretsDirectory
+	if directory == "" {
+		directory = os.Getenv(SecretsDirectoryEnvironmentalVariable)
+	}
+	var missing []string
+	for _, key := range keys {
+		valueKey := SecretValueKey(secret.Namespace, secret.Name, key)
+		if value, ok := secretValues[valueKey]; ok {
+			secret.Data[key] = value
+			continue
+		}
+		envVar := SecretEnvironmentalVariable(secret.Namespace, secret.Name, key)
+		if value, ok := os.LookupEnv(envVar); ok {
+			secret.Data[key] = []byte(value)
+			continue
+		}
+		if directory != "" {
+			value, err := ioutil.ReadFile(path.Join(directory, valueKey))
+			if err == nil {
+				secret.Data[key] = value
+				continue
+			}
+			missing = append(missing, fmt.Sprintf("  %s: set $%s or create %s", key, envVar, path.Join(directory, valueKey)))
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("  %s: set $%s or use --secrets-dir", key, envVar))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing values for Secret [%s]:\n%s", SecretValueKey(secret.Namespace, secret.Name, ""), strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// SecretLookup is an optional interface for a Deployable that looks up
+// Secret values when it is installed in Kubernetes, instead of when its
+// objects are defined.
+//
+// Apps codified with both function mode and secret lookup implement it.
+type SecretLookup interface {
+
+	// SecretLookups returns the keys to look up for each Secret,
+	// by SecretValueKey(namespace, name, "").
+	SecretLookups() map[string][]string
+}
+
+// LookupSecrets will look up the values for every Secret in objs that
+// has keys in lookups, with LookupSecretData().
+//
+// An error is returned if two keys are looked up from the same environmental
+// variable, or listing every Secret that is missing a value.
+func LookupSecrets(objs []runtime.Object, lookups map[string][]string) error {
+	err := CheckSecretLookups(lookups)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, obj := range objs {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			continue
+		}
+		keys := lookups[SecretValueKey(secret.Namespace, secret.Name, "")]
+		if len(keys) == 0 {
+			continue
+		}
+		err := LookupSecretData(secret, keys...)
+		if err != nil {
+			missing = append(missing, err.Error())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s", strings.Join(missing, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecretEnvironmentalVariable(t *testing.T) {
+	actual := SecretEnvironmentalVariable("default", "my-secret", "tls.crt")
+	expected := "NAML_SECRET_DEFAULT_MY_SECRET_TLS_CRT"
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+	actual = SecretEnvironmentalVariable("", "my-secret", "password")
+	expected = "NAML_SECRET_DEFAULT_MY_SECRET_PASSWORD"
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestSecretValueKey(t *testing.T) {
+	actual := SecretValueKey("", "my-secret", "password")
+	expected := "default/my-secret/password"
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestCheckSecretLookups(t *testing.T) {
+	err := CheckSecretLookups(map[string][]string{
+		"default/my-secret": {"password", "tls.crt"},
+		"web/my-secret":     {"password"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, lookups := range []map[string][]string{
+		{"default/my-secret": {"my-key", "my_key"}},
+		{"default/my-secret": {"password"}, "default-my/secret": {"password"}},
+		{"default/my-secret": {"Password", "password"}},
+	} {
+		err = CheckSecretLookups(lookups)
+		if err == nil {
+			t.Errorf("expected error for colliding lookups: %v", lookups)
+			continue
+		}
+		if !strings.Contains(err.Error(), "NAML_SECRET_DEFAULT_MY_SECRET_") {
+			t.Errorf("expected environmental variable in error: %v", err)
+		}
+	}
+}
+
+func TestCodifySecretLookupCollision(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Secret
+metadata:
+  name: my-secret
+data:
+  my-key: c2VjcmV0
+  my_key: c2VjcmV0
+`
+	_, err := Codify(bytes.NewBufferString(manifest), &CodifyValues{
+		AppNameLower: "app",
+		AppNameTitle: "App",
+		PackageName:  "main",
+		SecretLookup: true,
+	})
+	if err == nil {
+		t.Fatalf("expected error for colliding Secret keys")
+	}
+	if !strings.Contains(err.Error(), "NAML_SECRET_DEFAULT_MY_SECRET_MY_KEY") {
+		t.Errorf("expected environmental variable in error: %v", err)
+	}
+}
+
+func TestLookupSecretData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "naml-secrets")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.MkdirAll(path.Join(dir, "test", "lookup"), 0700)
+	if err != nil {
+		t.Fatalf("unable to create secret dir: %v", err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "test", "lookup", "file"), []byte("from-file"), 0600)
+	if err != nil {
+		t.Fatalf("unable to write secret file: %v", err)
+	}
+	os.Setenv("NAML_SECRET_TEST_LOOKUP_ENV", "from-env")
+	defer os.Unsetenv("NAML_SECRET_TEST_LOOKUP_ENV")
+	os.Setenv(SecretsDirectoryEnvironmentalVariable, dir)
+	defer os.Unsetenv(SecretsDirectoryEnvironmentalVariable)
+	SetSecretValues(map[string][]byte{
+		"test/lookup/map": []byte("from-map"),
+	})
+	defer delete(secretValues, "test/lookup/map")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "lookup",
+			Namespace: "test",
+		},
+	}
+	err = LookupSecretData(secret, "map", "env", "file")
+	if err != nil {
+		t.Fatalf("unexpected lookup error: %v", err)
+	}
+	for key, expected := range map[string]string{"map": "from-map", "env": "from-env", "file": "from-file"} {
+		if string(secret.Data[key]) != expected {
+			t.Errorf("expected %s for key %s, got %s", expected, key, string(secret.Data[key]))
+		}
+	}
+
+	err = LookupSecretData(secret, "missing")
+	if err == nil {
+		t.Fatalf("expected error for missing secret value")
+	}
+	if !strings.Contains(err.Error(), "NAML_SECRET_TEST_LOOKUP_MISSING") {
+		t.Errorf("expected missing environmental variable in error: %v", err)
+	}
+}
@@ -19,11 +19,16 @@
 //   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
 //   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
 //
+{{ if .Secrets }}
+{{ .Secrets }}
+{{- end }}
 
 package main
 
 import (
+	{{- if not .FunctionMode }}
 	"context"
+	{{- end }}
 	"fmt"
 	"os"
 
@@ -47,7 +52,9 @@ func main() {
 
 type {{ .AppNameTitle }} struct {
 	naml.AppMeta
+	{{- if not .FunctionMode }}
 	objects []runtime.Object
+	{{- end }}
 }
 
 func New{{ .AppNameTitle }}(name, description string) *{{ .AppNameTitle }} {
@@ -62,6 +69,59 @@ func New{{ .AppNameTitle }}(name, description string) *{{ .AppNameTitle }} {
 	}
 }
 
+{{ if .FunctionMode }}
+func (x *{{ .AppNameTitle }}) Install(client kubernetes.Interface) error {
+	if client == nil {
+		return nil
+	}
+	objs := x.Objects()
+	{{- if .SecretLookups }}
+	err := naml.LookupSecrets(objs, x.SecretLookups())
+	if err != nil {
+		return err
+	}
+	{{- end }}
+	for _, obj := range objs {
+		err := naml.Create(client, obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+{{- if .SecretLookups }}
+
+// SecretLookups are the keys looked up for each Secret when the
+// application is installed in Kubernetes.
+func (x *{{ .AppNameTitle }}) SecretLookups() map[string][]string {
+	return {{ .SecretLookups }}
+}
+{{- end }}
+
+func (x *{{ .AppNameTitle }}) Uninstall(client kubernetes.Interface) error {
+	if client == nil {
+		return nil
+	}
+	for _, obj := range x.Objects() {
+		err := naml.Delete(client, obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *{{ .AppNameTitle }}) Meta() *naml.AppMeta {
+	return &x.AppMeta
+}
+
+func (x *{{ .AppNameTitle }}) Objects() []runtime.Object {
+	return []runtime.Object{
+		{{ .Constructors }}
+	}
+}
+{{ .Functions }}
+{{ else }}
 func (x *{{ .AppNameTitle }}) Install(client kubernetes.Interface) error {
 	var err error
 	{{ .Install }}
@@ -81,3 +141,4 @@ func (x *{{ .AppNameTitle }}) Meta() *naml.AppMeta {
 func (x *{{ .AppNameTitle }}) Objects() []runtime.Object {
 	return x.objects
 }
+{{- end }}
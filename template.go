@@ -0,0 +1,99 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kris-nova/naml/codify"
+)
+
+// TemplateSchema will return a JSON schema that documents the variables
+// available in every template that can be overridden with CodifyValues.TemplateDir.
+//
+// The schema is generated from CodifyValues and codify.TemplateValues.
+func TemplateSchema() ([]byte, error) {
+	definitions := make(map[string]interface{})
+	for _, name := range codify.TemplateNames() {
+		var definition map[string]interface{}
+		switch name {
+		case codify.MainTemplate, codify.LibraryTemplate:
+			definition = templateSchema(reflect.TypeOf(CodifyValues{}))
+		default:
+			definition = templateSchema(reflect.TypeOf(codify.TemplateValues{}))
+			kind := strings.TrimSuffix(strings.TrimSuffix(name, ".install"), ".uninstall")
+			properties := definition["properties"].(map[string]interface{})
+			kubeObject := properties["KubeObject"].(map[string]interface{})
+			t := reflect.TypeOf(codify.TemplateKinds[kind]).Elem()
+			kubeObject["x-go-type"] = fmt.Sprintf("*%s.%s", t.PkgPath(), t.Name())
+		}
+		definition["description"] = fmt.Sprintf("Values for the %s%s template.", name, codify.TemplateExtension)
+		definitions[name] = definition
+	}
+	schema := map[string]interface{}{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "naml codify templates",
+		"description": fmt.Sprintf("Templates are Go text/template files in the template directory named after the template with the %s extension. Example: deployment.install%s", codify.TemplateExtension, codify.TemplateExtension),
+		"definitions": definitions,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// templateSchema will return the JSON schema for a Go type.
+func templateSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			property := templateSchema(field.Type)
+			if description := field.Tag.Get("description"); description != "" {
+				property["description"] = description
+			}
+			properties[field.Name] = property
+		}
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": templateSchema(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	}
+	return map[string]interface{}{"type": "object"}
+}
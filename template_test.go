@@ -0,0 +1,201 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const templateTestYAML = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: example
+`
+
+func templateTestValues(dir string) *CodifyValues {
+	return &CodifyValues{
+		AppNameLower: "app",
+		AppNameTitle: "App",
+		PackageName:  "main",
+		TemplateDir:  dir,
+	}
+}
+
+func TestTemplateDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "naml-templates")
+	if err != nil {
+		t.Fatalf("unable to create template directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	install := "\n\t// Custom template for {{ .KubeObject.Name }}\n\t{{ .GoName }}Namespace := {{ .Literal }}\n\tx.objects = append(x.objects, {{ .GoName }}Namespace)\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "namespace.install.tpl"), []byte(install), 0644)
+	if err != nil {
+		t.Fatalf("unable to write template: %v", err)
+	}
+
+	code, err := Codify(bytes.NewBufferString(templateTestYAML), templateTestValues(dir))
+	if err != nil {
+		t.Fatalf("unable to codify: %v", err)
+	}
+	if !strings.Contains(string(code), "// Custom template for example") {
+		t.Errorf("missing custom template in code:\n%s", string(code))
+	}
+	if strings.Contains(string(code), "client.CoreV1().Namespaces().Create") {
+		t.Errorf("unexpected built in template in code:\n%s", string(code))
+	}
+
+	// Without the template directory the built in templates are used
+	code, err = Codify(bytes.NewBufferString(templateTestYAML), templateTestValues(""))
+	if err != nil {
+		t.Fatalf("unable to codify: %v", err)
+	}
+	if strings.Contains(string(code), "// Custom template") {
+		t.Errorf("unexpected custom template in code:\n%s", string(code))
+	}
+}
+
+func TestTemplateDirConcurrent(t *testing.T) {
+	var dirs []string
+	for _, name := range []string{"first", "second"} {
+		dir, err := ioutil.TempDir("", "naml-templates")
+		if err != nil {
+			t.Fatalf("unable to create template directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		install := "\n\t// " + name + " template\n\t{{ .GoName }}Namespace := {{ .Literal }}\n\tx.objects = append(x.objects, {{ .GoName }}Namespace)\n"
+		err = ioutil.WriteFile(filepath.Join(dir, "namespace.install.tpl"), []byte(install), 0644)
+		if err != nil {
+			t.Fatalf("unable to write template: %v", err)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	// Each call to Codify must only use its own templates
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		name, dir := "first", dirs[0]
+		if i%2 == 1 {
+			name, dir = "second", dirs[1]
+		}
+		wg.Add(1)
+		go func(name, dir string) {
+			defer wg.Done()
+			code, err := Codify(bytes.NewBufferString(templateTestYAML), templateTestValues(dir))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !strings.Contains(string(code), "// "+name+" template") {
+				errs <- fmt.Errorf("missing %s template in code:\n%s", name, string(code))
+			}
+		}(name, dir)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestTemplateDirUnknown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "naml-templates")
+	if err != nil {
+		t.Fatalf("unable to create template directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "unknown.install.tpl"), []byte(""), 0644)
+	if err != nil {
+		t.Fatalf("unable to write template: %v", err)
+	}
+	_, err = Codify(bytes.NewBufferString(templateTestYAML), templateTestValues(dir))
+	if err == nil {
+		t.Errorf("expected error for unknown template")
+	}
+}
+
+func TestTemplateDirRenderError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "naml-templates")
+	if err != nil {
+		t.Fatalf("unable to create template directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "namespace.install.tpl"), []byte("{{ .Nope }}"), 0644)
+	if err != nil {
+		t.Fatalf("unable to write template: %v", err)
+	}
+	_, err = Codify(bytes.NewBufferString(templateTestYAML), templateTestValues(dir))
+	if err == nil {
+		t.Errorf("expected error for template that does not render")
+	}
+}
+
+func TestTemplateDirFunctionMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "naml-templates")
+	if err != nil {
+		t.Fatalf("unable to create template directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "namespace.install.tpl"), []byte(""), 0644)
+	if err != nil {
+		t.Fatalf("unable to write template: %v", err)
+	}
+	values := templateTestValues(dir)
+	values.FunctionMode = true
+	_, err = Codify(bytes.NewBufferString(templateTestYAML), values)
+	if err == nil {
+		t.Errorf("expected error for kind template in function mode")
+	}
+}
+
+func TestTemplateSchema(t *testing.T) {
+	raw, err := TemplateSchema()
+	if err != nil {
+		t.Fatalf("unable to generate schema: %v", err)
+	}
+	schema := struct {
+		Definitions map[string]struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		} `json:"definitions"`
+	}{}
+	err = json.Unmarshal(raw, &schema)
+	if err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	if _, ok := schema.Definitions["main.go"].Properties["AppNameTitle"]; !ok {
+		t.Errorf("missing main.go AppNameTitle")
+	}
+	kubeObject := schema.Definitions["deployment.install"].Properties["KubeObject"]
+	if kubeObject["x-go-type"] != "*k8s.io/api/apps/v1.Deployment" {
+		t.Errorf("unexpected deployment KubeObject: %v", kubeObject)
+	}
+}
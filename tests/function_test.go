@@ -0,0 +1,106 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kris-nova/naml"
+)
+
+// modeManifests are the manifests that are codified in every mode.
+var modeManifests = []string{
+	"test_comments.yaml",
+	"test_nginx.yaml",
+	"test_single_deploy.yaml",
+}
+
+func TestFunctionModeManifests(t *testing.T) {
+	testModeManifests(t, "functions", func(values *naml.CodifyValues) {
+		values.FunctionMode = true
+	})
+}
+
+func TestHoistManifests(t *testing.T) {
+	testModeManifests(t, "hoist", func(values *naml.CodifyValues) {
+		values.Hoist = true
+	})
+}
+
+// testModeManifests will codify every manifest in a mode, and check the
+// generated code against the golden file for the mode.
+//
+// The program for each mode must render the same objects as the program
+// codified in the default mode.
+func testModeManifests(t *testing.T, mode string, set func(values *naml.CodifyValues)) {
+	for _, file := range modeManifests {
+		t.Logf("testing %s [%s]", mode, file)
+		filename := filepath.Join("manifests", file)
+		values := CodifyValues(file)
+		set(values)
+		output, err := codifyFileWithValues(filename, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join("golden", strings.TrimSuffix(file, filepath.Ext(file))+"."+mode+".go.golden")
+		checkGolden(t, golden, output)
+
+		rendered, err := renderJSON(output)
+		if err != nil {
+			t.Fatalf("%s: %s: %v", mode, filename, err)
+		}
+		defaultOutput, err := codifyFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := renderJSON(defaultOutput)
+		if err != nil {
+			t.Fatalf("default: %s: %v", filename, err)
+		}
+		if !bytes.Equal(rendered, expected) {
+			t.Errorf("%s output does not match the default output: %s: %s", mode, filename, firstDifference(expected, rendered))
+		}
+	}
+}
+
+// renderJSON will compile the generated code, and return the
+// objects the program renders with "output -o json".
+func renderJSON(code []byte) ([]byte, error) {
+	program, err := naml.Compile(code)
+	if program != nil {
+		defer program.Remove()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile: %v", err)
+	}
+	stdout, stderr, err := program.Execute([]string{"output", "-o", "json"})
+	if err != nil {
+		return nil, fmt.Errorf("failed executing output: %v: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
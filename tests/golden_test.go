@@ -0,0 +1,127 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package tests
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kris-nova/naml"
+)
+
+// update will regenerate the golden files
+//
+//	go test ./tests -run 'TestGoldenManifests|TestFunctionModeManifests|TestHoistManifests' -update
+var update = flag.Bool("update", false, "update the golden files in tests/golden")
+
+func TestGoldenManifests(t *testing.T) {
+	files, err := ioutil.ReadDir("manifests")
+	if err != nil {
+		t.Fatalf("unable to list manifests directory: %v", err)
+	}
+	for _, file := range files {
+		t.Logf("testing golden [%s]", file.Name())
+		filename := filepath.Join("manifests", file.Name())
+		output, err := codifyFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The same input must always generate the same output
+		again, err := codifyFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(output, again) {
+			t.Errorf("non deterministic codify output: %s: %s", filename, firstDifference(output, again))
+			continue
+		}
+
+		golden := filepath.Join("golden", strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))+".go.golden")
+		checkGolden(t, golden, output)
+	}
+}
+
+// checkGolden will compare the output with the golden file, or
+// write the golden file when the tests are run with -update.
+func checkGolden(t *testing.T, golden string, output []byte) {
+	t.Helper()
+	if *update {
+		err := os.MkdirAll(filepath.Dir(golden), 0755)
+		if err != nil {
+			t.Fatalf("unable to create golden directory: %v", err)
+		}
+		err = ioutil.WriteFile(golden, output, 0644)
+		if err != nil {
+			t.Fatalf("unable to write golden file: %v", err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Errorf("unable to read golden file (run with -update): %v", err)
+		return
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("codify output does not match %s (run with -update): %s", golden, firstDifference(expected, output))
+	}
+}
+
+// codifyFile will codify a manifest with the test values.
+//
+// Partial output is allowed, as not every manifest can be codified completely.
+func codifyFile(filename string) ([]byte, error) {
+	return codifyFileWithValues(filename, CodifyValues(filename))
+}
+
+// codifyFileWithValues will codify a manifest with the values.
+func codifyFileWithValues(filename string, values *naml.CodifyValues) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file: %s: %v", filename, err)
+	}
+	output, err := naml.Codify(bytes.NewReader(data), values)
+	if err != nil && len(output) == 0 {
+		return nil, fmt.Errorf("unable to codify: %s: %v", filename, err)
+	}
+	return output, nil
+}
+
+// firstDifference will return the first line that is different.
+func firstDifference(expected, actual []byte) string {
+	expectedLines := strings.Split(string(expected), "\n")
+	actualLines := strings.Split(string(actual), "\n")
+	for i := 0; i < len(expectedLines) && i < len(actualLines); i++ {
+		if expectedLines[i] != actualLines[i] {
+			return fmt.Sprintf("line %d: expected %q, found %q", i+1, expectedLines[i], actualLines[i])
+		}
+	}
+	return fmt.Sprintf("expected %d lines, found %d lines", len(expectedLines), len(actualLines))
+}
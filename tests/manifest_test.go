@@ -62,13 +62,17 @@ func CodifyValues(name string) *naml.CodifyValues {
 
 // generateCompileRunYAML will build a Go program from YAML and try to compile and run it :)
 func generateCompileRunYAML(filename string) error {
+	return generateCompileRunYAMLWithValues(filename, CodifyValues(filename))
+}
+
+func generateCompileRunYAMLWithValues(filename string, values *naml.CodifyValues) error {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("unable to read file: %s: %v", filename, err)
 	}
 	buffer := bytes.Buffer{}
 	buffer.Write(data)
-	output, err := naml.Codify(&buffer, CodifyValues(filename))
+	output, err := naml.Codify(&buffer, values)
 	if err != nil && len(output) < 0 {
 		return fmt.Errorf("unable to codify: %s: %v", filename, err)
 	} else if err != nil && len(output) > 0 {
@@ -0,0 +1,110 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kris-nova/naml"
+)
+
+const secretLookupManifest = `apiVersion: v1
+kind: Secret
+metadata:
+  name: web-credentials
+  namespace: web
+type: Opaque
+data:
+  password: c2VjcmV0
+`
+
+// compileSecretLookup will codify the secret lookup manifest and compile it.
+func compileSecretLookup(t *testing.T, functions bool) *naml.Program {
+	values := CodifyValues("secret_lookup")
+	values.SecretLookup = true
+	values.FunctionMode = functions
+	output, err := naml.Codify(bytes.NewBufferString(secretLookupManifest), values)
+	if err != nil {
+		t.Fatalf("unable to codify: %v", err)
+	}
+	if bytes.Contains(output, []byte("c2VjcmV0")) {
+		t.Fatalf("expected the secret value to be left out of the source")
+	}
+	program, err := naml.Compile(output)
+	if err != nil {
+		t.Fatalf("unable to compile: %v", err)
+	}
+	return program
+}
+
+func TestSecretLookupFunctionsOutput(t *testing.T) {
+	program := compileSecretLookup(t, true)
+	defer program.Remove()
+
+	// Values are only looked up when installing in Kubernetes, so
+	// list and output work without them
+	for _, args := range [][]string{{"list"}, {"output"}} {
+		stdout, stderr, err := program.Execute(args)
+		if err != nil {
+			t.Fatalf("failed executing %v: %v: %s", args, err, stderr.String())
+		}
+		if strings.Contains(stderr.String(), "missing values") {
+			t.Errorf("unexpected lookup running %v: %s", args, stderr.String())
+		}
+		if args[0] == "output" && !strings.Contains(stdout.String(), "name: web-credentials") {
+			t.Errorf("expected the Secret in the output, got: %s", stdout.String())
+		}
+	}
+}
+
+func TestSecretLookupOutput(t *testing.T) {
+	program := compileSecretLookup(t, false)
+	defer program.Remove()
+
+	dir, err := ioutil.TempDir("", "naml-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.MkdirAll(filepath.Join(dir, "web", "web-credentials"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "web", "web-credentials", "password"), []byte("secret"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, err := program.Execute([]string{"--secrets-dir", dir, "output"})
+	if err != nil {
+		t.Fatalf("failed executing output: %v: %s", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "password: c2VjcmV0") {
+		t.Errorf("expected the looked up value in the output, got: %s", stdout.String())
+	}
+}
@@ -0,0 +1,81 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kris-nova/naml"
+)
+
+const unsupportedManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+  namespace: web
+data:
+  key: value
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: web-widget
+  namespace: web
+spec:
+  size: 3
+`
+
+func TestCodifyVerifyUnsupportedKind(t *testing.T) {
+	src, report, err := naml.CodifyVerify([]byte(unsupportedManifest), CodifyValues("unsupported"))
+	if err != nil {
+		t.Fatalf("unable to verify partial codify output: %v", err)
+	}
+	if len(src) == 0 {
+		t.Fatalf("expected the partial generated code")
+	}
+	if !report.Failed() {
+		t.Errorf("expected the report to fail with a missing object")
+	}
+	var found bool
+	for _, obj := range report.Objects {
+		switch obj.Kind {
+		case "Widget":
+			found = true
+			if !obj.Missing || !obj.Unsupported {
+				t.Errorf("expected the Widget to be missing and unsupported: %+v", obj)
+			}
+		case "ConfigMap":
+			if obj.Missing || len(obj.Differences) > 0 {
+				t.Errorf("expected the ConfigMap to verify: %+v", obj)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected the Widget in the report")
+	}
+	if !strings.Contains(report.String(), "[Widget web/web-widget] unsupported kind, missing from generated code") {
+		t.Errorf("unexpected report:\n%s", report.String())
+	}
+}
@@ -0,0 +1,618 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kris-nova/logger"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Transformer changes the objects of an application before they
+// are installed or output, without changing the application.
+//
+// Transformers are given copies of the objects, and can change
+// them in place, add objects or remove them.
+type Transformer interface {
+	Transform(objs []runtime.Object) ([]runtime.Object, error)
+}
+
+// TransformerFactory will return a Transformer for the argument
+// given on the command line with --transform name=arg
+type TransformerFactory func(arg string) (Transformer, error)
+
+// ObjectTransformerFunc is a Transformer that changes a single object
+// in place, and is called for every object.
+type ObjectTransformerFunc func(obj runtime.Object) error
+
+// Transform will call the function for every object
+func (f ObjectTransformerFunc) Transform(objs []runtime.Object) ([]runtime.Object, error) {
+	for _, obj := range objs {
+		err := f(obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+var (
+	// transformerRegistry are the transformers that can be
+	// selected from the command line by name.
+	transformerRegistry = map[string]TransformerFactory{
+		"namespace": func(arg string) (Transformer, error) {
+			if arg == "" {
+				return nil, fmt.Errorf("empty namespace")
+			}
+			return NamespaceTransformer(arg), nil
+		},
+		"labels": func(arg string) (Transformer, error) {
+			labels, err := parseKeyValues(arg)
+			return LabelsTransformer(labels), err
+		},
+		"annotations": func(arg string) (Transformer, error) {
+			annotations, err := parseKeyValues(arg)
+			return AnnotationsTransformer(annotations), err
+		},
+		"prefix": func(arg string) (Transformer, error) {
+			return PrefixTransformer(arg), nil
+		},
+		"suffix": func(arg string) (Transformer, error) {
+			return SuffixTransformer(arg), nil
+		},
+		"image": func(arg string) (Transformer, error) {
+			return ParseImageRewrite(arg)
+		},
+	}
+
+	// transformers is the pipeline of transformers, in order
+	transformers []Transformer
+)
+
+// RegisterTransformerAndExit will register the transformer or exit with an error message
+func RegisterTransformerAndExit(name string, factory TransformerFactory) {
+	err := RegisterTransformerAndError(name, factory)
+	if err != nil {
+		logger.Critical("%v", err)
+		os.Exit(1)
+	}
+}
+
+// RegisterTransformer will register a transformer that can be selected
+// from the command line with --transform name=arg
+func RegisterTransformer(name string, factory TransformerFactory) {
+	RegisterTransformerAndExit(name, factory)
+}
+
+func RegisterTransformerAndError(name string, factory TransformerFactory) error {
+	if name == "" || strings.Contains(name, "=") {
+		return fmt.Errorf("unable to register transformer with invalid name: %q", name)
+	}
+	if factory == nil {
+		return fmt.Errorf("unable to register nil transformer: %s", name)
+	}
+	if _, ok := transformerRegistry[name]; ok {
+		return fmt.Errorf("unable to register transformer %s: already registered", name)
+	}
+	transformerRegistry[name] = factory
+	return nil
+}
+
+// TransformerNames will return the sorted names of every registered transformer
+func TransformerNames() []string {
+	var names []string
+	for name := range transformerRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTransformer will return the registered transformer for
+// a command line value such as "namespace=production".
+func NewTransformer(value string) (Transformer, error) {
+	parts := strings.SplitN(value, "=", 2)
+	factory, ok := transformerRegistry[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown transformer %q: expected one of %s", parts[0], strings.Join(TransformerNames(), ", "))
+	}
+	var arg string
+	if len(parts) == 2 {
+		arg = parts[1]
+	}
+	t, err := factory(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transformer %q: %v", value, err)
+	}
+	return t, nil
+}
+
+// AddTransformers will add transformers to the end of the pipeline.
+func AddTransformers(ts ...Transformer) {
+	transformers = append(transformers, ts...)
+}
+
+// SetTransformers will replace the pipeline.
+func SetTransformers(ts ...Transformer) {
+	transformers = ts
+}
+
+// transformerInit will add the --transform values to the pipeline.
+func transformerInit(values []string) error {
+	for _, value := range values {
+		t, err := NewTransformer(value)
+		if err != nil {
+			return err
+		}
+		AddTransformers(t)
+	}
+	return nil
+}
+
+// Transforming will return true if the objects of an application are
+// changed before they are installed or output.
+func Transforming() bool {
+	return len(transformers) > 0 || len(patches) > 0 || len(imageRewrites) > 0 || profile != ""
+}
+
+// TransformedObjects will return copies of the objects of an application
+// after the selected profile, the patches, the pipeline of transformers
+// and every image rewrite. The objects of the application are not changed.
+//
+// The application must already be installed, either in Kubernetes
+// or "nowhere" with app.Install(nil).
+func TransformedObjects(app Deployable) ([]runtime.Object, error) {
+	objs, err := copyAppObjects(app)
+	if err != nil {
+		return nil, err
+	}
+	return transformObjects(app, objs)
+}
+
+// copyAppObjects will return deep copies of the objects of an application.
+func copyAppObjects(app Deployable) ([]runtime.Object, error) {
+	objs, err := AppObjects(app)
+	if err != nil {
+		return nil, err
+	}
+	var copies []runtime.Object
+	for _, obj := range objs {
+		copies = append(copies, obj.DeepCopyObject())
+	}
+	return copies, nil
+}
+
+// transformObjects will run the selected profile, the patches, the pipeline
+// of transformers and every image rewrite over copies of the objects of an
+// application.
+func transformObjects(app Deployable, transformed []runtime.Object) ([]runtime.Object, error) {
+	objs, err := profileTransform(app, transformed)
+	if !appsRun.skip(-1, err) {
+		if err != nil {
+			return nil, err
+		}
+		transformed = objs
+	}
+	for i, patch := range patches {
+		objs, err := patch.Transform(transformed)
+		if appsRun.skip(i, err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to patch app %s: %v", app.Meta().Name, err)
+		}
+		transformed = objs
+	}
+	for _, t := range transformers {
+		transformed, err = t.Transform(transformed)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform app %s: %v", app.Meta().Name, err)
+		}
+	}
+	for _, obj := range transformed {
+		RewriteImages(obj, imageRewrites)
+	}
+	err = SetObjectKinds(transformed)
+	if err != nil {
+		return nil, err
+	}
+	return transformed, nil
+}
+
+// unmatchedError is returned when the selected profile or
+// a patch does not match an application.
+type unmatchedError struct {
+	message string
+}
+
+func (e *unmatchedError) Error() string {
+	return e.message
+}
+
+// transformRun tracks the selected profile and the patches over
+// every application of a run with more than one application.
+type transformRun struct {
+	// matched is true for the profile (-1) and each patch by index
+	// once it matches an application.
+	matched map[int]bool
+
+	// unmatched is the first unmatched error of the profile (-1) and
+	// each patch by index, until it matches an application.
+	unmatched map[int]error
+}
+
+// appsRun is the current run with more than one application, or nil.
+var appsRun *transformRun
+
+// skip will return true when err is an unmatched error that is skipped
+// because more than one application is transformed.
+func (r *transformRun) skip(index int, err error) bool {
+	if r == nil {
+		return false
+	}
+	if _, ok := err.(*unmatchedError); ok {
+		if !r.matched[index] && r.unmatched[index] == nil {
+			r.unmatched[index] = err
+		}
+		return true
+	}
+	if err == nil {
+		r.matched[index] = true
+		delete(r.unmatched, index)
+	}
+	return false
+}
+
+// transformApps will run fn for every application in names.
+//
+// With more than one application the selected profile and every patch
+// only have to match one of them, and the applications they do not match
+// are left alone. It is an error if one does not match any application.
+func transformApps(names []string, fn func(name string) error) error {
+	if len(names) > 1 {
+		appsRun = &transformRun{
+			matched:   make(map[int]bool),
+			unmatched: make(map[int]error),
+		}
+		defer func() {
+			appsRun = nil
+		}()
+	}
+	for _, name := range names {
+		err := fn(name)
+		if err != nil {
+			return err
+		}
+	}
+	if appsRun == nil {
+		return nil
+	}
+	if _, ok := appsRun.unmatched[-1]; ok {
+		return fmt.Errorf("no app has profile %q", profile)
+	}
+	for i := range patches {
+		if err, ok := appsRun.unmatched[i]; ok {
+			return fmt.Errorf("unable to patch apps: %v in any app", err)
+		}
+	}
+	return nil
+}
+
+// InstallObjects will install the transformed objects of an application
+// with Create, instead of the Install() of the application.
+//
+// The Install() of the application only runs "nowhere" to register the
+// objects, so anything else it does with a client, such as waiting for
+// an object, does not run. Objects of any kind are created, including
+// custom resources.
+func InstallObjects(client kubernetes.Interface, app Deployable) error {
+	// Install the application "nowhere" to register the components in memory
+	err := app.Install(nil)
+	if err != nil {
+		return fmt.Errorf("unable to install app in memory: %v", err)
+	}
+	objs, err := copyAppObjects(app)
+	if err != nil {
+		return err
+	}
+	if lookup, ok := app.(SecretLookup); ok {
+		err = LookupSecrets(objs, lookup.SecretLookups())
+		if err != nil {
+			return err
+		}
+	}
+	objs, err = transformObjects(app, objs)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		err = Create(client, obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UninstallObjects will uninstall the transformed objects of an application
+// with Delete, instead of the Uninstall() of the application, which does
+// not run.
+//
+// Objects are deleted in the same order as they are installed, like the
+// Uninstall() that codify generates.
+func UninstallObjects(client kubernetes.Interface, app Deployable) error {
+	// Install the application "nowhere" to register the components in memory
+	err := app.Install(nil)
+	if err != nil {
+		return fmt.Errorf("unable to install app in memory: %v", err)
+	}
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		err = Delete(client, obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LabelsTransformer will add labels to every object, and to the
+// pod template of every workload. Selectors are not changed.
+func LabelsTransformer(labels map[string]string) Transformer {
+	return ObjectTransformerFunc(func(obj runtime.Object) error {
+		for _, m := range objectMetas(obj) {
+			m.SetLabels(mergeStrings(m.GetLabels(), labels))
+		}
+		return nil
+	})
+}
+
+// AnnotationsTransformer will add annotations to every object, and
+// to the pod template of every workload.
+func AnnotationsTransformer(annotations map[string]string) Transformer {
+	return ObjectTransformerFunc(func(obj runtime.Object) error {
+		for _, m := range objectMetas(obj) {
+			m.SetAnnotations(mergeStrings(m.GetAnnotations(), annotations))
+		}
+		return nil
+	})
+}
+
+// PrefixTransformer will add a prefix to the name of every object,
+// and update the references between the objects of the application.
+func PrefixTransformer(prefix string) Transformer {
+	return renameTransformer(func(name string) string {
+		return prefix + name
+	})
+}
+
+// SuffixTransformer will add a suffix to the name of every object,
+// and update the references between the objects of the application.
+func SuffixTransformer(suffix string) Transformer {
+	return renameTransformer(func(name string) string {
+		return name + suffix
+	})
+}
+
+// transformerFunc is a Transformer for the whole list of objects
+type transformerFunc func(objs []runtime.Object) ([]runtime.Object, error)
+
+func (f transformerFunc) Transform(objs []runtime.Object) ([]runtime.Object, error) {
+	return f(objs)
+}
+
+// renameTransformer will rename every object except Namespaces and
+// CustomResourceDefinitions, whose names can not change, and update
+// the references to the renamed objects.
+func renameTransformer(rename func(string) string) Transformer {
+	return transformerFunc(func(objs []runtime.Object) ([]runtime.Object, error) {
+		renamed := make(map[string]bool)
+		for _, obj := range objs {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, err
+			}
+			gvk, err := ObjectKind(obj)
+			if err != nil {
+				return nil, err
+			}
+			if gvk.Kind == "Namespace" || gvk.Kind == "CustomResourceDefinition" {
+				continue
+			}
+			renamed[gvk.Kind+"/"+accessor.GetName()] = true
+			accessor.SetName(rename(accessor.GetName()))
+		}
+		ref := func(kind string, name *string) {
+			if *name != "" && renamed[kind+"/"+*name] {
+				*name = rename(*name)
+			}
+		}
+		for _, obj := range objs {
+			switch x := obj.(type) {
+			case *rbacv1.RoleBinding:
+				ref(x.RoleRef.Kind, &x.RoleRef.Name)
+				renameSubjects(x.Subjects, ref)
+			case *rbacv1.ClusterRoleBinding:
+				ref(x.RoleRef.Kind, &x.RoleRef.Name)
+				renameSubjects(x.Subjects, ref)
+			case *appsv1.StatefulSet:
+				ref("Service", &x.Spec.ServiceName)
+			case *networkingv1.Ingress:
+				if x.Spec.DefaultBackend != nil && x.Spec.DefaultBackend.Service != nil {
+					ref("Service", &x.Spec.DefaultBackend.Service.Name)
+				}
+				for i := range x.Spec.TLS {
+					ref("Secret", &x.Spec.TLS[i].SecretName)
+				}
+				for i := range x.Spec.Rules {
+					if x.Spec.Rules[i].HTTP == nil {
+						continue
+					}
+					for j := range x.Spec.Rules[i].HTTP.Paths {
+						if service := x.Spec.Rules[i].HTTP.Paths[j].Backend.Service; service != nil {
+							ref("Service", &service.Name)
+						}
+					}
+				}
+			}
+			if spec, _ := PodSpec(obj); spec != nil {
+				renamePodSpec(spec, ref)
+			}
+		}
+		return objs, nil
+	})
+}
+
+func renameSubjects(subjects []rbacv1.Subject, ref func(string, *string)) {
+	for i := range subjects {
+		if subjects[i].Kind == rbacv1.ServiceAccountKind {
+			ref("ServiceAccount", &subjects[i].Name)
+		}
+	}
+}
+
+// renamePodSpec will update the references of a pod spec to
+// ServiceAccounts, ConfigMaps, Secrets and PersistentVolumeClaims.
+func renamePodSpec(spec *corev1.PodSpec, ref func(string, *string)) {
+	ref("ServiceAccount", &spec.ServiceAccountName)
+	ref("ServiceAccount", &spec.DeprecatedServiceAccount)
+	for i := range spec.ImagePullSecrets {
+		ref("Secret", &spec.ImagePullSecrets[i].Name)
+	}
+	for i := range spec.Volumes {
+		v := &spec.Volumes[i]
+		if v.ConfigMap != nil {
+			ref("ConfigMap", &v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			ref("Secret", &v.Secret.SecretName)
+		}
+		if v.PersistentVolumeClaim != nil {
+			ref("PersistentVolumeClaim", &v.PersistentVolumeClaim.ClaimName)
+		}
+		if v.Projected != nil {
+			for j := range v.Projected.Sources {
+				if s := v.Projected.Sources[j].ConfigMap; s != nil {
+					ref("ConfigMap", &s.Name)
+				}
+				if s := v.Projected.Sources[j].Secret; s != nil {
+					ref("Secret", &s.Name)
+				}
+			}
+		}
+	}
+	var containers []*corev1.Container
+	for i := range spec.InitContainers {
+		containers = append(containers, &spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		containers = append(containers, &spec.Containers[i])
+	}
+	for _, c := range containers {
+		for i := range c.EnvFrom {
+			if c.EnvFrom[i].ConfigMapRef != nil {
+				ref("ConfigMap", &c.EnvFrom[i].ConfigMapRef.Name)
+			}
+			if c.EnvFrom[i].SecretRef != nil {
+				ref("Secret", &c.EnvFrom[i].SecretRef.Name)
+			}
+		}
+		for i := range c.Env {
+			if c.Env[i].ValueFrom == nil {
+				continue
+			}
+			if s := c.Env[i].ValueFrom.ConfigMapKeyRef; s != nil {
+				ref("ConfigMap", &s.Name)
+			}
+			if s := c.Env[i].ValueFrom.SecretKeyRef; s != nil {
+				ref("Secret", &s.Name)
+			}
+		}
+	}
+}
+
+// objectMetas will return the metadata of an object, and the metadata
+// of its pod template for workloads.
+func objectMetas(obj runtime.Object) []metav1.Object {
+	var metas []metav1.Object
+	if accessor, err := meta.Accessor(obj); err == nil {
+		metas = append(metas, accessor)
+	}
+	switch x := obj.(type) {
+	case *appsv1.Deployment:
+		metas = append(metas, &x.Spec.Template.ObjectMeta)
+	case *appsv1.StatefulSet:
+		metas = append(metas, &x.Spec.Template.ObjectMeta)
+	case *appsv1.DaemonSet:
+		metas = append(metas, &x.Spec.Template.ObjectMeta)
+	case *batchv1.Job:
+		metas = append(metas, &x.Spec.Template.ObjectMeta)
+	case *batchv1.CronJob:
+		metas = append(metas, &x.Spec.JobTemplate.Spec.Template.ObjectMeta)
+	case *batchv1beta1.CronJob:
+		metas = append(metas, &x.Spec.JobTemplate.Spec.Template.ObjectMeta)
+	}
+	return metas
+}
+
+// mergeStrings will return a copy of a with the values of b
+func mergeStrings(a, b map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		merged[k] = v
+	}
+	return merged
+}
+
+// parseKeyValues will parse "key=value,key2=value2"
+func parseKeyValues(arg string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(arg, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("expected key=value: %q", pair)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
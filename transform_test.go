@@ -0,0 +1,187 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func transformApp() *testApp {
+	deployment := BusyboxDeployment("web")
+	deployment.Namespace = "web"
+	deployment.Spec.Template.Spec.ServiceAccountName = "web"
+	deployment.Spec.Template.Spec.Volumes = []corev1.Volume{{
+		Name:         "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}},
+	}}
+	return &testApp{
+		objects: []runtime.Object{
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+			&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "web"}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "web"}},
+			&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+			&rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "web"},
+				Subjects: []rbacv1.Subject{
+					{Kind: rbacv1.ServiceAccountKind, Name: "web", Namespace: "web"},
+					{Kind: rbacv1.ServiceAccountKind, Name: "default", Namespace: "kube-system"},
+				},
+			},
+			deployment,
+		},
+	}
+}
+
+func TestPrefixTransformer(t *testing.T) {
+	SetTransformers(PrefixTransformer("dev-"), SuffixTransformer("-1"))
+	defer SetTransformers()
+
+	objs, err := TransformedObjects(transformApp())
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	if name := objs[0].(*corev1.Namespace).Name; name != "web" {
+		t.Errorf("expected Namespace to keep its name, got %s", name)
+	}
+	binding := objs[4].(*rbacv1.ClusterRoleBinding)
+	if binding.Name != "dev-web-1" || binding.RoleRef.Name != "dev-web-1" || binding.Subjects[0].Name != "dev-web-1" {
+		t.Errorf("unexpected binding: %s %v %v", binding.Name, binding.RoleRef, binding.Subjects)
+	}
+	if binding.Subjects[1].Name != "default" {
+		t.Errorf("expected reference outside the app to keep its name, got %s", binding.Subjects[1].Name)
+	}
+	spec := objs[5].(*appsv1.Deployment).Spec.Template.Spec
+	if spec.ServiceAccountName != "dev-web-1" || spec.Volumes[0].ConfigMap.Name != "dev-web-config-1" {
+		t.Errorf("unexpected pod spec references: %s %s", spec.ServiceAccountName, spec.Volumes[0].ConfigMap.Name)
+	}
+}
+
+func TestLabelsTransformer(t *testing.T) {
+	transformer, err := NewTransformer("labels=team=web,tier=frontend")
+	if err != nil {
+		t.Fatalf("unable to create transformer: %v", err)
+	}
+	SetTransformers(transformer)
+	defer SetTransformers()
+
+	objs, err := TransformedObjects(transformApp())
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	deployment := objs[5].(*appsv1.Deployment)
+	for _, labels := range []map[string]string{deployment.Labels, deployment.Spec.Template.Labels} {
+		if labels["team"] != "web" || labels["tier"] != "frontend" {
+			t.Errorf("missing labels: %v", labels)
+		}
+	}
+	if _, ok := deployment.Spec.Selector.MatchLabels["team"]; ok {
+		t.Errorf("unexpected label in selector")
+	}
+}
+
+func TestRegisterTransformer(t *testing.T) {
+	err := RegisterTransformerAndError("replicas", func(arg string) (Transformer, error) {
+		return ObjectTransformerFunc(func(obj runtime.Object) error {
+			if deployment, ok := obj.(*appsv1.Deployment); ok {
+				replicas := int32(len(arg))
+				deployment.Spec.Replicas = &replicas
+			}
+			return nil
+		}), nil
+	})
+	if err != nil {
+		t.Fatalf("unable to register transformer: %v", err)
+	}
+	defer delete(transformerRegistry, "replicas")
+
+	err = RegisterTransformerAndError("replicas", nil)
+	if err == nil {
+		t.Errorf("expected error registering nil transformer")
+	}
+	err = transformerInit([]string{"replicas=xxx"})
+	if err != nil {
+		t.Fatalf("unable to init transformers: %v", err)
+	}
+	defer SetTransformers()
+	objs, err := TransformedObjects(transformApp())
+	if err != nil {
+		t.Fatalf("unable to transform: %v", err)
+	}
+	if replicas := *objs[5].(*appsv1.Deployment).Spec.Replicas; replicas != 3 {
+		t.Errorf("expected 3 replicas, got %d", replicas)
+	}
+
+	_, err = NewTransformer("unknown=value")
+	if err == nil || !strings.Contains(err.Error(), "namespace") {
+		t.Errorf("expected error listing transformers, got %v", err)
+	}
+}
+
+func TestInstallObjects(t *testing.T) {
+	server, client := newTestAPIServer(t)
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("example.com/v1")
+	widget.SetKind("Widget")
+	widget.SetName("example")
+	app := &testApp{
+		objects: []runtime.Object{
+			&apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"}},
+			BusyboxDeployment("example"),
+			widget,
+		},
+	}
+	SetTransformers(NamespaceTransformer("staging"))
+	defer SetTransformers()
+
+	// Every kind is installed and uninstalled with the transforms
+	err := InstallObjects(client, app)
+	if err != nil {
+		t.Fatalf("unable to install: %v", err)
+	}
+	err = UninstallObjects(client, app)
+	if err != nil {
+		t.Fatalf("unable to uninstall: %v", err)
+	}
+	expected := []string{
+		"POST /apis/apiextensions.k8s.io/v1/customresourcedefinitions",
+		"POST /apis/apps/v1/namespaces/staging/deployments",
+		"POST /apis/example.com/v1/namespaces/staging/widgets",
+		"DELETE /apis/apiextensions.k8s.io/v1/customresourcedefinitions/widgets.example.com",
+		"DELETE /apis/apps/v1/namespaces/staging/deployments/example",
+		"DELETE /apis/example.com/v1/namespaces/staging/widgets/example",
+	}
+	if strings.Join(server.requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected requests:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(server.requests, "\n"))
+	}
+}
@@ -0,0 +1,533 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// OpenAPIVersion is the Kubernetes version of the embedded OpenAPI schema.
+const OpenAPIVersion string = "v1.22.0"
+
+//go:embed openapi/kubernetes-v1.22.0.json.gz
+var openAPIGzip []byte
+
+var (
+	openAPIOnce sync.Once
+	openAPI     *openAPIDocument
+	openAPIErr  error
+)
+
+// openAPIDocument is the embedded OpenAPI v2 document.
+type openAPIDocument struct {
+	Definitions map[string]*openAPISchema `json:"definitions"`
+
+	// kinds is the name of the definition for each kind
+	kinds map[schema.GroupVersionKind]string
+}
+
+// openAPISchema is the subset of an OpenAPI v2 or v3 schema that naml
+// validates. It is used for both the Kubernetes schema and the
+// openAPIV3Schema of CustomResourceDefinitions.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPIAdditional        `json:"additionalProperties,omitempty"`
+	Items                *openAPIItems             `json:"items,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	MinItems             *int                      `json:"minItems,omitempty"`
+	MaxItems             *int                      `json:"maxItems,omitempty"`
+
+	GroupVersionKinds     []schema.GroupVersionKind `json:"x-kubernetes-group-version-kind,omitempty"`
+	IntOrString           bool                      `json:"x-kubernetes-int-or-string,omitempty"`
+	PreserveUnknownFields bool                      `json:"x-kubernetes-preserve-unknown-fields,omitempty"`
+	EmbeddedResource      bool                      `json:"x-kubernetes-embedded-resource,omitempty"`
+	ListType              string                    `json:"x-kubernetes-list-type,omitempty"`
+	ListMapKeys           []string                  `json:"x-kubernetes-list-map-keys,omitempty"`
+}
+
+// openAPIAdditional is additionalProperties, which is either a
+// schema or a bool in OpenAPI v3.
+type openAPIAdditional struct {
+	Allowed bool
+	Schema  *openAPISchema
+}
+
+func (a *openAPIAdditional) UnmarshalJSON(raw []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		a.Allowed = true
+		return json.Unmarshal(raw, &a.Schema)
+	}
+	return json.Unmarshal(raw, &a.Allowed)
+}
+
+// openAPIItems is items, which is either a schema or a list
+// of schemas in OpenAPI v3. Only a single schema is validated.
+type openAPIItems struct {
+	Schema *openAPISchema
+}
+
+func (i *openAPIItems) UnmarshalJSON(raw []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		return json.Unmarshal(raw, &i.Schema)
+	}
+	return nil
+}
+
+// loadOpenAPI will decompress and decode the embedded schema once.
+func loadOpenAPI() (*openAPIDocument, error) {
+	openAPIOnce.Do(func() {
+		r, err := gzip.NewReader(bytes.NewReader(openAPIGzip))
+		if err != nil {
+			openAPIErr = fmt.Errorf("unable to read OpenAPI schema: %v", err)
+			return
+		}
+		raw, err := ioutil.ReadAll(r)
+		if err != nil {
+			openAPIErr = fmt.Errorf("unable to read OpenAPI schema: %v", err)
+			return
+		}
+		doc := &openAPIDocument{}
+		err = json.Unmarshal(raw, doc)
+		if err != nil {
+			openAPIErr = fmt.Errorf("unable to decode OpenAPI schema: %v", err)
+			return
+		}
+		var names []string
+		for name := range doc.Definitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		doc.kinds = make(map[schema.GroupVersionKind]string)
+		for _, name := range names {
+			for _, gvk := range doc.Definitions[name].GroupVersionKinds {
+				if _, ok := doc.kinds[gvk]; !ok {
+					doc.kinds[gvk] = name
+				}
+			}
+		}
+		openAPI = doc
+	})
+	return openAPI, openAPIErr
+}
+
+// ValidationReport is the result of validating every object
+// of an application against its schema.
+type ValidationReport struct {
+	Objects []*ValidationObject
+}
+
+// ValidationObject is the result of validating a single object.
+type ValidationObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	// Schema is the name of the schema the object was validated against.
+	Schema string
+
+	// Skipped is true if there is no schema for the kind.
+	Skipped bool
+
+	// Errors are the violations found for each field
+	Errors field.ErrorList
+}
+
+// Failed will return true if any object has a violation.
+func (r *ValidationReport) Failed() bool {
+	for _, obj := range r.Objects {
+		if len(obj.Errors) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// String will return the human readable report
+func (r *ValidationReport) String() string {
+	var report string
+	for _, obj := range r.Objects {
+		name := obj.Name
+		if obj.Namespace != "" {
+			name = fmt.Sprintf("%s/%s", obj.Namespace, obj.Name)
+		}
+		switch {
+		case obj.Skipped:
+			report = fmt.Sprintf("%s[%s %s] skipped: no schema\n", report, obj.Kind, name)
+		case len(obj.Errors) == 0:
+			report = fmt.Sprintf("%s[%s %s] ok\n", report, obj.Kind, name)
+		default:
+			report = fmt.Sprintf("%s[%s %s] %d error(s)\n", report, obj.Kind, name, len(obj.Errors))
+			for _, e := range obj.Errors {
+				report = fmt.Sprintf("%s    %s\n", report, e.Error())
+			}
+		}
+	}
+	return report
+}
+
+// RunValidate will find an application by name, install it "nowhere"
+// and Validate it.
+func RunValidate(appName string) (*ValidationReport, error) {
+	app := Find(appName)
+	if app == nil {
+		return nil, fmt.Errorf("unable to find app: %s", appName)
+	}
+
+	err := Configure(app)
+	if err != nil {
+		return nil, err
+	}
+
+	// Install the application "nowhere" to register the components in memory
+	err = app.Install(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to install app in memory: %v", err)
+	}
+	return Validate(app)
+}
+
+// Validate will check every object of an application against the
+// embedded Kubernetes OpenAPI schema, or against the openAPIV3Schema
+// of a CustomResourceDefinition in the application.
+//
+// The application must already be installed, either in Kubernetes
+// or "nowhere" with app.Install(nil).
+func Validate(app Deployable) (*ValidationReport, error) {
+	doc, err := loadOpenAPI()
+	if err != nil {
+		return nil, err
+	}
+	objs, err := TransformedObjects(app)
+	if err != nil {
+		return nil, err
+	}
+
+	// Custom resources are validated with the CRDs in the app
+	crds := make(map[schema.GroupVersionKind]*openAPISchema)
+	for _, obj := range objs {
+		crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+		if !ok {
+			continue
+		}
+		for _, version := range crd.Spec.Versions {
+			if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			raw, err := json.Marshal(version.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode schema of %s: %v", crd.Name, err)
+			}
+			s := &openAPISchema{}
+			err = json.Unmarshal(raw, s)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode schema of %s: %v", crd.Name, err)
+			}
+			crds[schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}] = s
+		}
+	}
+
+	report := &ValidationReport{}
+	for _, obj := range objs {
+		u, err := outputUnstructured(obj)
+		if err != nil {
+			return nil, err
+		}
+		gvk := u.GroupVersionKind()
+		vobj := &ValidationObject{
+			Kind:      gvk.Kind,
+			Namespace: u.GetNamespace(),
+			Name:      u.GetName(),
+		}
+		report.Objects = append(report.Objects, vobj)
+		v := &validator{definitions: doc.Definitions}
+		if name, ok := doc.kinds[gvk]; ok {
+			vobj.Schema = name
+			vobj.Errors = v.validate(doc.Definitions[name], u.Object, nil)
+			continue
+		}
+		if s, ok := crds[gvk]; ok {
+			vobj.Schema = fmt.Sprintf("%s/%s %s", gvk.Group, gvk.Version, gvk.Kind)
+			vobj.Errors = v.validateResource(s, u.Object)
+			continue
+		}
+		vobj.Skipped = true
+	}
+	return report, nil
+}
+
+// validator checks values against a schema, and resolves
+// references with definitions.
+type validator struct {
+	definitions map[string]*openAPISchema
+}
+
+// validateResource will validate the root of a custom resource, where
+// apiVersion, kind and metadata are always allowed.
+func (v *validator) validateResource(s *openAPISchema, obj map[string]interface{}) field.ErrorList {
+	resource := make(map[string]interface{})
+	for key, value := range obj {
+		if key == "apiVersion" || key == "kind" || key == "metadata" {
+			continue
+		}
+		resource[key] = value
+	}
+	return v.validate(s, resource, nil)
+}
+
+// validate will validate a value against a schema at path.
+// The path is nil for the root of an object.
+func (v *validator) validate(s *openAPISchema, value interface{}, path *field.Path) field.ErrorList {
+	s, err := v.resolve(s)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	if value == nil {
+		return nil
+	}
+	if s.IntOrString || s.Format == "int-or-string" {
+		switch value.(type) {
+		case string, int64, float64:
+			return nil
+		}
+		return field.ErrorList{typeError(path, value, "integer or string")}
+	}
+
+	var errs field.ErrorList
+	switch s.Type {
+	case "object":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errs, typeError(path, value, "object"))
+		}
+		errs = append(errs, v.validateObject(s, m, path)...)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(errs, typeError(path, value, "array"))
+		}
+		errs = append(errs, v.validateArray(s, items, path)...)
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(errs, typeError(path, value, "string"))
+		}
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			errs = append(errs, field.Invalid(path, str, fmt.Sprintf("must be at least %d characters", *s.MinLength)))
+		}
+		if s.MaxLength != nil && len(str) > *s.MaxLength {
+			errs = append(errs, field.TooLong(path, str, *s.MaxLength))
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile(s.Pattern)
+			if err == nil && !re.MatchString(str) {
+				errs = append(errs, field.Invalid(path, str, fmt.Sprintf("must match %s", s.Pattern)))
+			}
+		}
+	case "integer", "number":
+		n, ok := number(value)
+		if !ok || (s.Type == "integer" && n != math.Trunc(n)) {
+			return append(errs, typeError(path, value, s.Type))
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			errs = append(errs, field.Invalid(path, value, fmt.Sprintf("must be greater than or equal to %v", *s.Minimum)))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			errs = append(errs, field.Invalid(path, value, fmt.Sprintf("must be less than or equal to %v", *s.Maximum)))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(errs, typeError(path, value, "boolean"))
+		}
+	default:
+		// A schema without a type is validated as an object if it
+		// has properties, and otherwise allows any value.
+		if m, ok := value.(map[string]interface{}); ok && len(s.Properties) > 0 {
+			errs = append(errs, v.validateObject(s, m, path)...)
+		}
+	}
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		var valid []string
+		for _, e := range s.Enum {
+			valid = append(valid, fmt.Sprintf("%v", e))
+		}
+		errs = append(errs, field.NotSupported(path, value, valid))
+	}
+	return errs
+}
+
+// validateObject will validate the required and known fields of an object.
+func (v *validator) validateObject(s *openAPISchema, m map[string]interface{}, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for _, name := range s.Required {
+		// Typed objects can not tell an empty string or a
+		// nil pointer from a missing field, so both are missing.
+		if value, ok := m[name]; !ok || value == nil || value == "" {
+			errs = append(errs, field.Required(child(path, name), ""))
+		}
+	}
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if property, ok := s.Properties[key]; ok {
+			errs = append(errs, v.validate(property, m[key], child(path, key))...)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+			errs = append(errs, v.validate(s.AdditionalProperties.Schema, m[key], child(path, key))...)
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Allowed:
+		case s.PreserveUnknownFields:
+		case s.EmbeddedResource && (key == "apiVersion" || key == "kind" || key == "metadata"):
+		case len(s.Properties) == 0 && s.AdditionalProperties == nil:
+			// An object without properties allows any field
+		default:
+			errs = append(errs, field.Forbidden(child(path, key), "unknown field"))
+		}
+	}
+	return errs
+}
+
+// validateArray will validate every item of an array, and that
+// the keys of map and set lists are unique.
+func (v *validator) validateArray(s *openAPISchema, items []interface{}, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		errs = append(errs, field.Invalid(path, len(items), fmt.Sprintf("must have at least %d items", *s.MinItems)))
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		errs = append(errs, field.TooMany(path, len(items), *s.MaxItems))
+	}
+	seen := make(map[string]bool)
+	for i, item := range items {
+		ipath := path.Index(i)
+		if s.Items != nil && s.Items.Schema != nil {
+			errs = append(errs, v.validate(s.Items.Schema, item, ipath)...)
+		}
+		var key interface{}
+		switch s.ListType {
+		case "map":
+			m, ok := item.(map[string]interface{})
+			if !ok || len(s.ListMapKeys) == 0 {
+				continue
+			}
+			var values []interface{}
+			for _, k := range s.ListMapKeys {
+				values = append(values, m[k])
+			}
+			key = values
+		case "set":
+			key = item
+		default:
+			continue
+		}
+		raw, _ := json.Marshal(key)
+		if seen[string(raw)] {
+			errs = append(errs, field.Duplicate(ipath, key))
+		}
+		seen[string(raw)] = true
+	}
+	return errs
+}
+
+// resolve will follow the $ref of a schema.
+func (v *validator) resolve(s *openAPISchema) (*openAPISchema, error) {
+	for s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		ref, ok := v.definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("unable to find schema %s", s.Ref)
+		}
+		s = ref
+	}
+	return s, nil
+}
+
+// child will return the path of a field, where a nil
+// path is the root of an object.
+func child(path *field.Path, name string) *field.Path {
+	if path == nil {
+		return field.NewPath(name)
+	}
+	return path.Child(name)
+}
+
+// typeError will return an error for a value of the wrong type.
+func typeError(path *field.Path, value interface{}, expected string) *field.Error {
+	switch value.(type) {
+	case map[string]interface{}:
+		value = "object"
+	case []interface{}:
+		value = "array"
+	}
+	return field.Invalid(path, value, fmt.Sprintf("must be %s", expected))
+}
+
+// number will return a numeric value as a float64.
+func number(value interface{}) (float64, bool) {
+	switch x := value.(type) {
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// enumContains will return true if value is one of enum.
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprintf("%v", e) == fmt.Sprintf("%v", value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidate(t *testing.T) {
+	invalid := BusyboxDeployment("invalid")
+	invalid.Spec.Template.Spec.Containers[0].Name = ""
+	invalid.Spec.Selector = nil
+	app := &testApp{
+		objects: []runtime.Object{
+			BusyboxDeployment("valid"),
+			invalid,
+		},
+	}
+	report, err := Validate(app)
+	if err != nil {
+		t.Fatalf("unable to validate: %v", err)
+	}
+	if !report.Failed() {
+		t.Fatalf("expected validation to fail:\n%s", report)
+	}
+	if len(report.Objects[0].Errors) != 0 {
+		t.Errorf("unexpected errors for valid deployment: %v", report.Objects[0].Errors)
+	}
+	expected := []string{
+		"spec.template.spec.containers[0].name: Required value",
+		"spec.selector: Required value",
+	}
+	for _, e := range expected {
+		if !strings.Contains(report.String(), e) {
+			t.Errorf("missing %q in report:\n%s", e, report)
+		}
+	}
+}
+
+func TestValidateCustomResource(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "examples.naml.dev"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "naml.dev",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Example", Plural: "examples"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    "v1",
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {
+								Type:     "object",
+								Required: []string{"size"},
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"size": {Type: "integer"},
+									"mode": {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"fast"`)}}},
+								},
+							},
+						},
+					},
+				},
+			}},
+		},
+	}
+	example := func(name string, spec map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		u.SetAPIVersion("naml.dev/v1")
+		u.SetKind("Example")
+		u.SetName(name)
+		return u
+	}
+	unknown := example("unknown", map[string]interface{}{})
+	unknown.SetAPIVersion("naml.dev/v2")
+	app := &testApp{
+		objects: []runtime.Object{
+			crd,
+			example("valid", map[string]interface{}{"size": int64(3), "mode": "fast"}),
+			example("invalid", map[string]interface{}{"size": "large", "mode": "slow", "color": "blue"}),
+			unknown,
+		},
+	}
+	report, err := Validate(app)
+	if err != nil {
+		t.Fatalf("unable to validate: %v", err)
+	}
+	if errs := report.Objects[0].Errors; len(errs) != 0 {
+		t.Errorf("unexpected errors for CustomResourceDefinition: %v", errs)
+	}
+	if errs := report.Objects[1].Errors; len(errs) != 0 {
+		t.Errorf("unexpected errors for valid resource: %v", errs)
+	}
+	if !report.Objects[3].Skipped {
+		t.Errorf("expected resource without schema to be skipped")
+	}
+	expected := []string{
+		`spec.color: Forbidden: unknown field`,
+		`spec.mode: Unsupported value: "slow": supported values: "fast"`,
+		`spec.size: Invalid value: "large": must be integer`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(report.String(), e) {
+			t.Errorf("missing %q in report:\n%s", e, report)
+		}
+	}
+}
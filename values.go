@@ -0,0 +1,345 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Configurable is an optional interface that a Deployable can implement
+// to be configured at install time with --values and --set.
+//
+//	type Values struct {
+//		Replicas int32  `json:"replicas"`
+//		Image    string `json:"image"`
+//	}
+//
+//	func (a *App) Values() interface{} {
+//		return &a.values
+//	}
+type Configurable interface {
+
+	// Values will return a pointer to the typed values of the application.
+	// The values are set before Install is called.
+	Values() interface{}
+}
+
+// ValueSetSeparator separates the path and the value of a --set
+const ValueSetSeparator = "="
+
+// valuesFiles are the --values files, decoded in order
+var valuesFiles []string
+
+// valueSets are the --set values, set in order after valuesFiles
+var valueSets []*ValueSet
+
+// ValueSet is a single value to set by path, such as
+// image.tag=v1.2.3
+type ValueSet struct {
+	Path  []string
+	Value string
+}
+
+// ParseValueSet will parse a path=value string.
+//
+// The path is separated by dots and uses the json names of
+// the fields, or the keys of a map.
+func ParseValueSet(set string) (*ValueSet, error) {
+	spl := strings.SplitN(set, ValueSetSeparator, 2)
+	if len(spl) != 2 || strings.TrimSpace(spl[0]) == "" {
+		return nil, fmt.Errorf("invalid --set %q, expected path=value", set)
+	}
+	path := strings.Split(strings.TrimSpace(spl[0]), ".")
+	for _, key := range path {
+		if key == "" {
+			return nil, fmt.Errorf("invalid --set %q, empty key in path", set)
+		}
+	}
+	return &ValueSet{
+		Path:  path,
+		Value: spl[1],
+	}, nil
+}
+
+// String will return the path=value of a ValueSet.
+func (s *ValueSet) String() string {
+	return strings.Join(s.Path, ".") + ValueSetSeparator + s.Value
+}
+
+// Apply will set the value at the path in values, which must be a pointer.
+//
+// The value is parsed to the type of the field. Unknown keys are an error.
+func (s *ValueSet) Apply(values interface{}) error {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("values must be a non-nil pointer, got %T", values)
+	}
+	err := setPath(v, s.Path, 0, s.Value)
+	if err != nil {
+		return fmt.Errorf("--set %s: %v", s, err)
+	}
+	return nil
+}
+
+// DecodeValues will decode YAML or JSON values into values, which must
+// be a pointer. Unknown keys are an error.
+func DecodeValues(data []byte, values interface{}) error {
+	raw, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+	if string(bytes.TrimSpace(raw)) == "null" {
+		// Empty file
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(values)
+}
+
+// SetValues will set the --values files and the --set values that
+// every Configurable application is configured with.
+func SetValues(files []string, sets ...string) error {
+	var parsed []*ValueSet
+	for _, set := range sets {
+		s, err := ParseValueSet(set)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, s)
+	}
+	valuesFiles = files
+	valueSets = parsed
+	return nil
+}
+
+// valuesInit will set the --values and --set flags.
+func valuesInit(files, sets []string) error {
+	return SetValues(files, sets...)
+}
+
+// Configure will decode the --values files and then every --set value
+// into the values of an application.
+//
+// An application that is not Configurable is an error if any values
+// are set.
+func Configure(app Deployable) error {
+	if len(valuesFiles) == 0 && len(valueSets) == 0 {
+		return nil
+	}
+	configurable, ok := app.(Configurable)
+	if !ok {
+		return fmt.Errorf("app %s does not accept values, it does not implement naml.Configurable", app.Meta().Name)
+	}
+	values := configurable.Values()
+	if v := reflect.ValueOf(values); v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("values of app %s must be a non-nil pointer, got %T", app.Meta().Name, values)
+	}
+	for _, file := range valuesFiles {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("unable to read values file: %v", err)
+		}
+		err = DecodeValues(data, values)
+		if err != nil {
+			return fmt.Errorf("invalid values file %s: %v", file, err)
+		}
+	}
+	for _, set := range valueSets {
+		err := set.Apply(values)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPath will set the value at path[i:] in v, allocating
+// pointers and maps on the way.
+func setPath(v reflect.Value, path []string, i int, value string) error {
+	if v.Kind() == reflect.Ptr && i < len(path) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setPath(v.Elem(), path, i, value)
+	}
+	if i == len(path) {
+		return setScalar(v, value)
+	}
+	key := path[i]
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := valueField(v, key)
+		if !ok {
+			return fmt.Errorf("unknown key %q", strings.Join(path[:i+1], "."))
+		}
+		return setPath(field, path, i+1, value)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: unsupported map key %s", strings.Join(path[:i], "."), v.Type().Key())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		k := reflect.ValueOf(key).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(k); existing.IsValid() {
+			elem.Set(existing)
+		}
+		err := setPath(elem, path, i+1, value)
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(k, elem)
+		return nil
+	}
+	return fmt.Errorf("unknown key %q, %s is a %s", strings.Join(path[:i+1], "."), strings.Join(path[:i], "."), v.Type())
+}
+
+// valueField will find the field of a struct by its json name,
+// or by its Go name ignoring case. Embedded structs are searched
+// like encoding/json does.
+func valueField(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					if !embedded.CanSet() {
+						continue
+					}
+					embedded.Set(reflect.New(embedded.Type().Elem()))
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if field, ok := valueField(embedded, key); ok {
+					return field, true
+				}
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			// Unexported
+			continue
+		}
+		if name == key || (name == "" && strings.EqualFold(f.Name, key)) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setScalar will parse value to the type of v.
+//
+// Slices are comma separated, and structs and maps are JSON.
+func setScalar(v reflect.Value, value string) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setScalar(v.Elem(), value)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("cannot use %q as %s", value, v.Type())
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot use %q as %s", value, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot use %q as %s", value, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot use %q as %s", value, v.Type())
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), 0, 0)
+		if value != "" {
+			for _, item := range strings.Split(value, ",") {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				err := setScalar(elem, strings.TrimSpace(item))
+				if err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, elem)
+			}
+		}
+		v.Set(slice)
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.DisallowUnknownFields()
+		ptr := reflect.New(v.Type())
+		err := decoder.Decode(ptr.Interface())
+		if err != nil {
+			return fmt.Errorf("cannot use %q as %s: %v", value, v.Type(), err)
+		}
+		v.Set(ptr.Elem())
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+type testValues struct {
+	Replicas int32             `json:"replicas"`
+	Debug    bool              `json:"debug"`
+	Timeout  time.Duration     `json:"timeout"`
+	Args     []string          `json:"args"`
+	Image    *testImageValues  `json:"image"`
+	Labels   map[string]string `json:"labels"`
+}
+
+type testImageValues struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+}
+
+type configurableApp struct {
+	*testApp
+	values testValues
+}
+
+func (c *configurableApp) Values() interface{} {
+	return &c.values
+}
+
+func (c *configurableApp) Install(client kubernetes.Interface) error {
+	deployment := BusyboxDeployment("web")
+	deployment.Spec.Replicas = &c.values.Replicas
+	if c.values.Image != nil {
+		deployment.Spec.Template.Spec.Containers[0].Image = c.values.Image.Repository + ":" + c.values.Image.Tag
+	}
+	c.objects = []runtime.Object{deployment}
+	return nil
+}
+
+func TestValueSet(t *testing.T) {
+	values := &testValues{}
+	for _, set := range []string{
+		"replicas=3",
+		"debug=true",
+		"timeout=30s",
+		"args=--a, --b",
+		"image.repository=busybox",
+		"image.tag=1.34",
+		"labels.team=web",
+	} {
+		s, err := ParseValueSet(set)
+		if err != nil {
+			t.Fatalf("unable to parse %s: %v", set, err)
+		}
+		err = s.Apply(values)
+		if err != nil {
+			t.Fatalf("unable to apply %s: %v", set, err)
+		}
+	}
+	if values.Replicas != 3 || !values.Debug || values.Timeout != 30*time.Second {
+		t.Errorf("unexpected values: %+v", values)
+	}
+	if len(values.Args) != 2 || values.Args[1] != "--b" {
+		t.Errorf("unexpected args: %v", values.Args)
+	}
+	if values.Image == nil || values.Image.Repository != "busybox" || values.Image.Tag != "1.34" {
+		t.Errorf("unexpected image: %+v", values.Image)
+	}
+	if values.Labels["team"] != "web" {
+		t.Errorf("unexpected labels: %v", values.Labels)
+	}
+
+	for set, expected := range map[string]string{
+		"replicas=three":   "cannot use \"three\" as int32",
+		"image.digest=abc": "unknown key \"image.digest\"",
+		"debug.value=true": "unknown key \"debug.value\"",
+	} {
+		s, err := ParseValueSet(set)
+		if err != nil {
+			t.Fatalf("unable to parse %s: %v", set, err)
+		}
+		err = s.Apply(values)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q for %s, got %v", expected, set, err)
+		}
+	}
+	for _, set := range []string{"replicas", "=3", "image..tag=1"} {
+		_, err := ParseValueSet(set)
+		if err == nil {
+			t.Errorf("expected error parsing %s", set)
+		}
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "values.yaml")
+	err := ioutil.WriteFile(file, []byte("replicas: 2\nimage:\n  repository: busybox\n  tag: \"1.33\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetValues([]string{file}, "image.tag=1.34")
+	if err != nil {
+		t.Fatalf("unable to set values: %v", err)
+	}
+	defer SetValues(nil)
+
+	app := &configurableApp{testApp: &testApp{}}
+	err = Configure(app)
+	if err != nil {
+		t.Fatalf("unable to configure: %v", err)
+	}
+	err = app.Install(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := app.Objects()[0].(*appsv1.Deployment)
+	if *deployment.Spec.Replicas != 2 {
+		t.Errorf("expected 2 replicas, got %d", *deployment.Spec.Replicas)
+	}
+	if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "busybox:1.34" {
+		t.Errorf("expected --set to win over --values, got %s", image)
+	}
+
+	// Unknown keys and wrong types in a values file are an error
+	for _, data := range []string{"replica: 2\n", "replicas: two\n"} {
+		err = ioutil.WriteFile(file, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = Configure(&configurableApp{testApp: &testApp{}})
+		if err == nil {
+			t.Errorf("expected error for values %q", data)
+		}
+	}
+
+	// Values for an app that is not Configurable are an error
+	err = Configure(&testApp{})
+	if err == nil {
+		t.Errorf("expected error configuring an app without values")
+	}
+}
@@ -0,0 +1,442 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kris-nova/naml/codify"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// verifySkippedKinds are kinds that codify will intentionally skip,
+// as they are managed by other objects in Kubernetes.
+var verifySkippedKinds = map[string]bool{
+	"ReplicaSet": true,
+	"Endpoints":  true,
+}
+
+// VerifyReport is the result of a codify round trip check.
+type VerifyReport struct {
+	Objects []*VerifyObject
+}
+
+// VerifyObject is the result of a codify round trip check for a single object.
+type VerifyObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	// Missing is true if the object is in the input YAML
+	// but not in the generated program output.
+	Missing bool
+
+	// Unexpected is true if the object is in the generated
+	// program output but not in the input YAML.
+	Unexpected bool
+
+	// Skipped is true if codify intentionally skips the kind.
+	Skipped bool
+
+	// Unsupported is true if the kind is not known to codify
+	// or to the Kubernetes scheme.
+	Unsupported bool
+
+	// Differences are the differences found for each field
+	Differences []string
+}
+
+// Failed will return true if any object has been lost or changed.
+func (r *VerifyReport) Failed() bool {
+	for _, obj := range r.Objects {
+		if obj.Missing && !obj.Skipped {
+			return true
+		}
+		if obj.Unexpected || len(obj.Differences) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// String will return the human readable report
+func (r *VerifyReport) String() string {
+	var report string
+	for _, obj := range r.Objects {
+		name := obj.Name
+		if obj.Namespace != "" {
+			name = fmt.Sprintf("%s/%s", obj.Namespace, obj.Name)
+		}
+		switch {
+		case obj.Skipped:
+			report = fmt.Sprintf("%s[%s %s] skipped\n", report, obj.Kind, name)
+		case obj.Missing && obj.Unsupported:
+			report = fmt.Sprintf("%s[%s %s] unsupported kind, missing from generated code\n", report, obj.Kind, name)
+		case obj.Missing:
+			report = fmt.Sprintf("%s[%s %s] missing from generated code\n", report, obj.Kind, name)
+		case obj.Unexpected:
+			report = fmt.Sprintf("%s[%s %s] not found in input YAML\n", report, obj.Kind, name)
+		case len(obj.Differences) == 0:
+			report = fmt.Sprintf("%s[%s %s] ok\n", report, obj.Kind, name)
+		default:
+			report = fmt.Sprintf("%s[%s %s] %d difference(s)\n", report, obj.Kind, name, len(obj.Differences))
+			for _, d := range obj.Differences {
+				report = fmt.Sprintf("%s    %s\n", report, d)
+			}
+		}
+	}
+	return report
+}
+
+// Verify will check that generated source code is a faithful representation
+// of the input YAML.
+//
+// The source code is compiled with Compile(), and the resulting Program is
+// executed with "output -o json". Each object is then semantically compared
+// with the input YAML, ignoring only the codify.StrippedFields.
+func Verify(input, src []byte) (*VerifyReport, error) {
+	program, err := Compile(src)
+	if program != nil {
+		defer program.Remove()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile generated code: %v", err)
+	}
+	stdout, stderr, err := program.Execute([]string{"output", "-o", "json"})
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute generated program: %v: %s", err, stderr.String())
+	}
+	return verifyObjects(input, stdout.Bytes())
+}
+
+// CodifyVerify will codify input YAML and Verify the generated code.
+//
+// Input that can only be partially codified is still verified, so the
+// report lists every object that is missing from the generated code.
+// The generated code is returned with the report.
+func CodifyVerify(input []byte, values *CodifyValues) ([]byte, *VerifyReport, error) {
+	if values.LibraryMode {
+		return nil, nil, fmt.Errorf("verify is not supported in library mode")
+	}
+	src, err := Codify(bytes.NewReader(input), values)
+	if err != nil && len(src) == 0 {
+		return nil, nil, err
+	}
+	report, err := Verify(input, src)
+	if err != nil {
+		return src, nil, fmt.Errorf("unable to verify: %v", err)
+	}
+	return src, report, nil
+}
+
+// verifyObject is a single object found in either the
+// input YAML or the generated program output.
+type verifyObject struct {
+	kind      string
+	namespace string
+	name      string
+	object    runtime.Object
+}
+
+func (o *verifyObject) key() string {
+	return fmt.Sprintf("%s/%s/%s", o.kind, o.namespace, o.name)
+}
+
+// verifyObjects will compare input YAML with the JSON output
+// of a generated program.
+func verifyObjects(input, output []byte) (*VerifyReport, error) {
+	expected, err := verifyDecodeYAML(input)
+	if err != nil {
+		return nil, err
+	}
+	var raws []json.RawMessage
+	err = json.Unmarshal(output, &raws)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse generated program output: %v", err)
+	}
+	actual := make(map[string]json.RawMessage)
+	var actualOrder []string
+	for _, raw := range raws {
+		partial := struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}{}
+		err = json.Unmarshal(raw, &partial)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse generated object: %v", err)
+		}
+		o := &verifyObject{
+			kind:      partial.Kind,
+			namespace: partial.Metadata.Namespace,
+			name:      partial.Metadata.Name,
+		}
+		actual[o.key()] = raw
+		actualOrder = append(actualOrder, o.key())
+	}
+
+	report := &VerifyReport{}
+	found := make(map[string]bool)
+	for _, e := range expected {
+		result := &VerifyObject{
+			Kind:      e.kind,
+			Namespace: e.namespace,
+			Name:      e.name,
+		}
+		report.Objects = append(report.Objects, result)
+		raw, ok := actual[e.key()]
+		if !ok {
+			result.Missing = true
+			result.Skipped = verifySkippedKinds[e.kind]
+			_, result.Unsupported = e.object.(*unstructured.Unstructured)
+			continue
+		}
+		found[e.key()] = true
+
+		// Decode the generated object into the same type as the input
+		// so both sides have the same zero values when marshaled.
+		generated := reflect.New(reflect.TypeOf(e.object).Elem()).Interface()
+		err = json.Unmarshal(raw, generated)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode generated %s: %v", e.key(), err)
+		}
+		expectedMap, err := verifyToMap(e.object)
+		if err != nil {
+			return nil, err
+		}
+		actualMap, err := verifyToMap(generated)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range append(codify.StrippedFields[""], codify.StrippedFields[e.kind]...) {
+			verifyRemoveField(expectedMap, strings.Split(field, "."))
+			verifyRemoveField(actualMap, strings.Split(field, "."))
+		}
+		result.Differences = verifyDiff("", expectedMap, actualMap)
+	}
+	for _, key := range actualOrder {
+		if found[key] {
+			continue
+		}
+		parts := strings.SplitN(key, "/", 3)
+		report.Objects = append(report.Objects, &VerifyObject{
+			Kind:       parts[0],
+			Namespace:  parts[1],
+			Name:       parts[2],
+			Unexpected: true,
+		})
+	}
+	return report, nil
+}
+
+// verifyDecodeYAML will decode every object in the input YAML
+// the same way codify splits and decodes the input.
+func verifyDecodeYAML(input []byte) ([]*verifyObject, error) {
+	var objects []*verifyObject
+	for _, document := range splitDocuments(input) {
+		decoded, err := verifyDecode(cleanRaw(document))
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, decoded...)
+	}
+	return objects, nil
+}
+
+func verifyDecode(raw []byte) ([]*verifyObject, error) {
+	var objects []*verifyObject
+	if len(raw) <= 1 {
+		return objects, nil
+	}
+	decoded, codifier, err := codify.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode input YAML: %v", err)
+	}
+	if codifier != nil {
+		return verifyObjectFor(decoded, codifier.GroupVersionKind.Kind)
+	}
+	serializer := scheme.Codecs.UniversalDeserializer()
+	decoded, gvk, err := serializer.Decode(raw, nil, nil)
+	if err != nil {
+		decoded, gvk, err = serializer.Decode(raw, nil, &apiextensionsv1.CustomResourceDefinition{})
+		if err != nil || gvk.Kind != "CustomResourceDefinition" {
+			// Unsupported kinds are still listed in the report
+			return verifyDecodeUnstructured(raw)
+		}
+	}
+	if list, ok := decoded.(*corev1.List); ok {
+		for _, item := range list.Items {
+			items, err := verifyDecode(item.Raw)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, items...)
+		}
+		return objects, nil
+	}
+	return verifyObjectFor(decoded, gvk.Kind)
+}
+
+// verifyDecodeUnstructured will decode an object of a kind that is
+// unknown to codify and the scheme.
+func verifyDecodeUnstructured(raw []byte) ([]*verifyObject, error) {
+	data, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode input YAML: %v", err)
+	}
+	u := &unstructured.Unstructured{}
+	err = u.UnmarshalJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode input YAML: %v", err)
+	}
+	return verifyObjectFor(u, u.GetKind())
+}
+
+func verifyObjectFor(decoded runtime.Object, kind string) ([]*verifyObject, error) {
+	accessor, err := meta.Accessor(decoded)
+	if err != nil {
+		return nil, err
+	}
+	return []*verifyObject{{
+		kind:      kind,
+		namespace: accessor.GetNamespace(),
+		name:      accessor.GetName(),
+		object:    decoded,
+	}}, nil
+}
+
+func verifyToMap(obj interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal object: %v", err)
+	}
+	m := make(map[string]interface{})
+	err = json.Unmarshal(raw, &m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal object: %v", err)
+	}
+	return m, nil
+}
+
+// verifyRemoveField will remove a field by path, where
+// "*" will match every item in a list.
+func verifyRemoveField(obj interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	switch x := obj.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(x, path[0])
+			return
+		}
+		verifyRemoveField(x[path[0]], path[1:])
+	case []interface{}:
+		if path[0] != "*" {
+			return
+		}
+		for _, item := range x {
+			verifyRemoveField(item, path[1:])
+		}
+	}
+}
+
+// verifyDiff will return a human readable difference for every
+// field that is not semantically equal.
+func verifyDiff(path string, expected, actual interface{}) []string {
+	var diffs []string
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected %s, found %s", verifyPath(path), verifyValue(expected), verifyValue(actual))}
+		}
+		keys := make(map[string]bool)
+		for k := range e {
+			keys[k] = true
+		}
+		for k := range a {
+			keys[k] = true
+		}
+		var sorted []string
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffs = append(diffs, verifyDiff(verifyJoin(path, k), e[k], a[k])...)
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return []string{fmt.Sprintf("%s: expected %s, found %s", verifyPath(path), verifyValue(expected), verifyValue(actual))}
+		}
+		for i := range e {
+			diffs = append(diffs, verifyDiff(fmt.Sprintf("%s[%d]", path, i), e[i], a[i])...)
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %s, found %s", verifyPath(path), verifyValue(expected), verifyValue(actual)))
+		}
+	}
+	return diffs
+}
+
+func verifyJoin(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+func verifyPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func verifyValue(value interface{}) string {
+	if value == nil {
+		return "<missing>"
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(raw)
+}
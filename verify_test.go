@@ -0,0 +1,93 @@
+//
+// Copyright © 2021 Kris Nóva <kris@nivenly.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+//   ███╗   ██╗ █████╗ ███╗   ███╗██╗
+//   ████╗  ██║██╔══██╗████╗ ████║██║
+//   ██╔██╗ ██║███████║██╔████╔██║██║
+//   ██║╚██╗██║██╔══██║██║╚██╔╝██║██║
+//   ██║ ╚████║██║  ██║██║ ╚═╝ ██║███████╗
+//   ╚═╝  ╚═══╝╚═╝  ╚═╝╚═╝     ╚═╝╚══════╝
+//
+
+package naml
+
+import (
+	"strings"
+	"testing"
+)
+
+const verifyTestInput = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: example
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.14.2
+        resources:
+          limits:
+            memory: 128Mi
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: example-abc
+  namespace: default
+`
+
+func TestVerifyObjectsOk(t *testing.T) {
+	// Resources are stripped by codify and should be ignored
+	output := `[{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"example","namespace":"default"},
+"spec":{"template":{"spec":{"containers":[{"name":"nginx","image":"nginx:1.14.2"}]}}},"status":{}}]`
+	report, err := verifyObjects([]byte(verifyTestInput), []byte(output))
+	if err != nil {
+		t.Fatalf("unable to verify: %v", err)
+	}
+	if report.Failed() {
+		t.Errorf("unexpected failure: %s", report.String())
+	}
+	if len(report.Objects) != 2 || !report.Objects[1].Skipped {
+		t.Errorf("expected skipped ReplicaSet: %s", report.String())
+	}
+}
+
+func TestVerifyObjectsDifferences(t *testing.T) {
+	output := `[{"apiVersion":"apps/appsv1","kind":"Deployment","metadata":{"name":"example","namespace":"default"},
+"spec":{"template":{"spec":{"containers":[{"name":"nginx","image":"nginx:latest"}]}}}},
+{"apiVersion":"v1","kind":"Service","metadata":{"name":"example","namespace":"default"}}]`
+	report, err := verifyObjects([]byte(verifyTestInput), []byte(output))
+	if err != nil {
+		t.Fatalf("unable to verify: %v", err)
+	}
+	if !report.Failed() {
+		t.Errorf("expected failure: %s", report.String())
+	}
+	differences := report.Objects[0].Differences
+	if len(differences) != 2 {
+		t.Fatalf("expected 2 differences, found %d: %s", len(differences), report.String())
+	}
+	if !strings.HasPrefix(differences[0], "apiVersion:") {
+		t.Errorf("unexpected difference: %s", differences[0])
+	}
+	if !strings.HasPrefix(differences[1], "spec.template.spec.containers[0].image:") {
+		t.Errorf("unexpected difference: %s", differences[1])
+	}
+	if !report.Objects[2].Unexpected {
+		t.Errorf("expected unexpected Service: %s", report.String())
+	}
+}